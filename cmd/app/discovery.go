@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/deviceinsight/eventhub-metrics/internal/blobstorage"
+	"github.com/deviceinsight/eventhub-metrics/internal/collector"
+	"github.com/deviceinsight/eventhub-metrics/internal/config"
+	"github.com/deviceinsight/eventhub-metrics/internal/metrics"
+	"github.com/deviceinsight/eventhub-metrics/internal/rest"
+	"github.com/deviceinsight/eventhub-metrics/internal/tablestorage"
+	"github.com/deviceinsight/eventhub-metrics/internal/tracing"
+)
+
+// checkpointDiscovery caches the consumer groups discovered in the configured storage accounts, since walking all
+// containers and tables is far more expensive than reading the checkpoints of the known consumer groups. Every storage
+// account is discovered on its own, so an unreachable account doesn't affect the others.
+type checkpointDiscovery struct {
+	credential  *azidentity.DefaultAzureCredential
+	blobClients *blobstorage.ClientPool
+	metrics     metrics.Service
+	cfg         *config.Config
+
+	discoverContainers func(ctx context.Context, storageAccountCfg config.BlobStorageConfig) (
+		blobstorage.StoredGroupsMap, error)
+	discoverTables func(ctx context.Context, storageAccountCfg config.TableStorageConfig) (
+		tablestorage.StoredGroupsMap, error)
+	now func() time.Time
+
+	mu         sync.Mutex
+	containers map[string]discoveredAccount[blobstorage.StoredGroupsMap]
+	tables     map[string]discoveredAccount[tablestorage.StoredGroupsMap]
+
+	refreshRequested atomic.Bool
+}
+
+// discoveredAccount holds the consumer groups last discovered in a storage account.
+type discoveredAccount[T any] struct {
+	storedGroups T
+	discoveredAt time.Time
+}
+
+func newCheckpointDiscovery(credential *azidentity.DefaultAzureCredential, blobClients *blobstorage.ClientPool,
+	metricsService metrics.Service, cfg *config.Config) *checkpointDiscovery {
+
+	d := &checkpointDiscovery{
+		credential:  credential,
+		blobClients: blobClients,
+		metrics:     metricsService,
+		cfg:         cfg,
+		now:         time.Now,
+		containers:  make(map[string]discoveredAccount[blobstorage.StoredGroupsMap]),
+		tables:      make(map[string]discoveredAccount[tablestorage.StoredGroupsMap]),
+	}
+	d.discoverContainers = func(ctx context.Context, storageAccountCfg config.BlobStorageConfig) (
+		blobstorage.StoredGroupsMap, error) {
+		return getCheckpointContainerInfos(ctx, blobClients, storageAccountCfg)
+	}
+	d.discoverTables = func(ctx context.Context, storageAccountCfg config.TableStorageConfig) (
+		tablestorage.StoredGroupsMap, error) {
+		return getCheckpointTableInfos(ctx, credential, storageAccountCfg)
+	}
+	return d
+}
+
+// get returns the discovered consumer groups. A storage account is rediscovered once collector.discoveryInterval
+// elapsed, a refresh was requested or its last discovery failed. If the discovery of an account fails, its
+// previously discovered consumer groups are used, unless the access was denied. Otherwise, the account is skipped
+// and its error is returned along with the consumer groups of all other accounts.
+func (d *checkpointDiscovery) get(ctx context.Context) (blobstorage.StoredGroupsMap, tablestorage.StoredGroupsMap,
+	error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	refresh := d.refreshRequested.Swap(false)
+
+	ctx, span := tracing.Start(ctx, "discoverCheckpointStores")
+	defer span.End()
+
+	var errs []error
+
+	storedGroups := make(blobstorage.StoredGroupsMap)
+	for _, storageAccountCfg := range d.cfg.StorageAccounts {
+		discovered, err := discoverAccount(ctx, d, d.containers, storageAccountCfg.Endpoint, refresh,
+			metrics.StageBlobList, func(ctx context.Context) (blobstorage.StoredGroupsMap, error) {
+				return d.discoverContainers(ctx, storageAccountCfg)
+			})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		maps.Copy(storedGroups, discovered)
+	}
+
+	storedTableGroups := make(tablestorage.StoredGroupsMap)
+	for _, storageAccountCfg := range d.cfg.TableStorageAccounts {
+		discovered, err := discoverAccount(ctx, d, d.tables, storageAccountCfg.Endpoint, refresh,
+			metrics.StageTableList, func(ctx context.Context) (tablestorage.StoredGroupsMap, error) {
+				return d.discoverTables(ctx, storageAccountCfg)
+			})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		maps.Copy(storedTableGroups, discovered)
+	}
+
+	err := errors.Join(errs...)
+	tracing.SetError(span, err)
+
+	slog.Debug("discovered checkpoint stores", "containers", len(storedGroups), "tables", len(storedTableGroups))
+
+	return storedGroups, storedTableGroups, err
+}
+
+// discoverAccount returns the consumer groups of a storage account, which are rediscovered if necessary. It returns
+// an error if the account is skipped.
+func discoverAccount[T any](ctx context.Context, d *checkpointDiscovery,
+	accounts map[string]discoveredAccount[T], endpoint string, refresh bool, stage string,
+	discover func(ctx context.Context) (T, error)) (T, error) {
+
+	account, cached := accounts[endpoint]
+	if cached && !refresh && d.now().Sub(account.discoveredAt) < d.cfg.Collector.DiscoveryInterval {
+		return account.storedGroups, nil
+	}
+
+	storedGroups, err := discover(ctx)
+	if err == nil {
+		accounts[endpoint] = discoveredAccount[T]{storedGroups: storedGroups, discoveredAt: d.now()}
+		return storedGroups, nil
+	}
+
+	d.metrics.RecordStageError(stage)
+
+	if cached && !errors.Is(err, rest.ErrAuthentication) {
+		slog.Warn("failed to refresh checkpoint discovery, using previously discovered consumer groups",
+			"storageAccount", endpoint, "error", err, "discoveredAt", account.discoveredAt)
+		return account.storedGroups, nil
+	}
+
+	delete(accounts, endpoint)
+	errorClass := collector.ClassifyError(err)
+	slog.Warn("skipping storage account whose checkpoints can't be discovered", "storageAccount", endpoint,
+		"errorClass", errorClass, "error", err)
+	d.metrics.RecordStorageAccountError(endpoint, errorClass)
+
+	var empty T
+	return empty, fmt.Errorf("failed to discover checkpoint stores of storage account %s: %w", endpoint, err)
+}
+
+// ServeHTTP requests a rediscovery of the checkpoint stores in the next collection cycle.
+func (d *checkpointDiscovery) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	slog.Info("checkpoint discovery refresh requested")
+	d.refreshRequested.Store(true)
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deviceinsight/eventhub-metrics/internal/blobstorage"
+	"github.com/deviceinsight/eventhub-metrics/internal/config"
+	"github.com/deviceinsight/eventhub-metrics/internal/metrics"
+	"github.com/deviceinsight/eventhub-metrics/internal/rest"
+	"github.com/deviceinsight/eventhub-metrics/internal/tablestorage"
+)
+
+func TestDiscoverySkipsFailingStorageAccount(t *testing.T) {
+	cfg := &config.Config{
+		StorageAccounts: []config.BlobStorageConfig{
+			{Endpoint: "failing.blob.core.windows.net"},
+			{Endpoint: "healthy.blob.core.windows.net"},
+		},
+		TableStorageAccounts: []config.TableStorageConfig{{Endpoint: "healthy.table.core.windows.net"}},
+	}
+	metricsService := newDiscoveryMetrics()
+	discovery := newTestDiscovery(cfg, metricsService)
+	discovery.discoverContainers = func(_ context.Context, storageAccountCfg config.BlobStorageConfig) (
+		blobstorage.StoredGroupsMap, error) {
+		if storageAccountCfg.Endpoint == "failing.blob.core.windows.net" {
+			return nil, errors.New("unreachable")
+		}
+		return blobGroups(storageAccountCfg.Endpoint), nil
+	}
+
+	storedGroups, storedTableGroups, err := discovery.get(context.Background())
+	if err == nil {
+		t.Fatal("expected the error of the failing storage account")
+	}
+
+	if len(storedGroups) != 1 || len(storedTableGroups) != 1 {
+		t.Fatalf("expected the consumer groups of the healthy accounts, got %v and %v", storedGroups,
+			storedTableGroups)
+	}
+	if metricsService.storageAccountErrors["failing.blob.core.windows.net"] != "unknown" ||
+		metricsService.stageErrors[metrics.StageBlobList] != 1 {
+		t.Fatalf("expected the failing storage account to be reported, got %+v", metricsService)
+	}
+}
+
+func TestDiscoveryReusesCacheWithinInterval(t *testing.T) {
+	cfg := &config.Config{
+		Collector:       config.CollectorConfig{DiscoveryInterval: 10 * time.Minute},
+		StorageAccounts: []config.BlobStorageConfig{{Endpoint: "account.blob.core.windows.net"}},
+	}
+	discovery := newTestDiscovery(cfg, newDiscoveryMetrics())
+
+	now := time.Now()
+	discovery.now = func() time.Time { return now }
+	discoveries := countDiscoveries(discovery)
+
+	getDiscovered(t, discovery)
+	now = now.Add(5 * time.Minute)
+	getDiscovered(t, discovery)
+	if *discoveries != 1 {
+		t.Fatalf("expected the cached discovery to be reused, got %d discoveries", *discoveries)
+	}
+
+	now = now.Add(5 * time.Minute)
+	getDiscovered(t, discovery)
+	if *discoveries != 2 {
+		t.Fatalf("expected a rediscovery after the interval, got %d discoveries", *discoveries)
+	}
+}
+
+func TestDiscoveryRefreshRequest(t *testing.T) {
+	cfg := &config.Config{
+		Collector:       config.CollectorConfig{DiscoveryInterval: time.Hour},
+		StorageAccounts: []config.BlobStorageConfig{{Endpoint: "account.blob.core.windows.net"}},
+	}
+	discovery := newTestDiscovery(cfg, newDiscoveryMetrics())
+	discoveries := countDiscoveries(discovery)
+
+	getDiscovered(t, discovery)
+
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		rec := httptest.NewRecorder()
+		discovery.ServeHTTP(rec, httptest.NewRequest(method, "/api/v1/discovery/refresh", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("expected %s to be rejected, got %d", method, rec.Code)
+		}
+	}
+	getDiscovered(t, discovery)
+	if *discoveries != 1 {
+		t.Fatalf("expected a rejected request not to refresh the discovery, got %d discoveries", *discoveries)
+	}
+
+	rec := httptest.NewRecorder()
+	discovery.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/discovery/refresh", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected the refresh to be accepted, got %d", rec.Code)
+	}
+
+	getDiscovered(t, discovery)
+	getDiscovered(t, discovery)
+	if *discoveries != 2 {
+		t.Fatalf("expected a single rediscovery after the refresh, got %d discoveries", *discoveries)
+	}
+}
+
+func TestDiscoveryFallsBackToPreviousResult(t *testing.T) {
+	cfg := &config.Config{
+		StorageAccounts:      []config.BlobStorageConfig{{Endpoint: "account.blob.core.windows.net"}},
+		TableStorageAccounts: []config.TableStorageConfig{{Endpoint: "account.table.core.windows.net"}},
+	}
+	metricsService := newDiscoveryMetrics()
+	discovery := newTestDiscovery(cfg, metricsService)
+	getDiscovered(t, discovery)
+
+	discovery.discoverContainers = func(context.Context, config.BlobStorageConfig) (blobstorage.StoredGroupsMap,
+		error) {
+		return nil, errors.New("unreachable")
+	}
+	discovery.discoverTables = func(context.Context, config.TableStorageConfig) (tablestorage.StoredGroupsMap,
+		error) {
+		return nil, errors.New("unreachable")
+	}
+
+	storedGroups, storedTableGroups := getDiscovered(t, discovery)
+	if len(storedGroups) != 1 || len(storedTableGroups) != 1 {
+		t.Fatalf("expected the previously discovered consumer groups, got %v and %v", storedGroups,
+			storedTableGroups)
+	}
+	if metricsService.stageErrors[metrics.StageBlobList] != 1 || metricsService.stageErrors[metrics.StageTableList] != 1 {
+		t.Fatalf("expected a failed discovery of each store type, got %v", metricsService.stageErrors)
+	}
+	if len(metricsService.storageAccountErrors) != 0 {
+		t.Fatalf("expected no storage account to be skipped, got %v", metricsService.storageAccountErrors)
+	}
+}
+
+func TestDiscoveryDoesNotFallBackOnAuthenticationError(t *testing.T) {
+	cfg := &config.Config{
+		StorageAccounts: []config.BlobStorageConfig{{Endpoint: "account.blob.core.windows.net"}},
+	}
+	discovery := newTestDiscovery(cfg, newDiscoveryMetrics())
+	getDiscovered(t, discovery)
+
+	discovery.discoverContainers = func(context.Context, config.BlobStorageConfig) (blobstorage.StoredGroupsMap,
+		error) {
+		return nil, fmt.Errorf("%w: forbidden", rest.ErrAuthentication)
+	}
+
+	storedGroups, _, err := discovery.get(context.Background())
+	if !errors.Is(err, rest.ErrAuthentication) {
+		t.Fatalf("expected %v, got %v", rest.ErrAuthentication, err)
+	}
+	if len(storedGroups) != 0 {
+		t.Fatalf("expected the consumer groups of the denied account to be dropped, got %v", storedGroups)
+	}
+}
+
+func newTestDiscovery(cfg *config.Config, metricsService metrics.Service) *checkpointDiscovery {
+	discovery := newCheckpointDiscovery(nil, blobstorage.NewClientPool(nil), metricsService, cfg)
+	discovery.discoverContainers = func(_ context.Context, storageAccountCfg config.BlobStorageConfig) (
+		blobstorage.StoredGroupsMap, error) {
+		return blobGroups(storageAccountCfg.Endpoint), nil
+	}
+	discovery.discoverTables = func(_ context.Context, storageAccountCfg config.TableStorageConfig) (
+		tablestorage.StoredGroupsMap, error) {
+		return tablestorage.StoredGroupsMap{
+			{Endpoint: storageAccountCfg.Endpoint, Table: "checkpoints"}: {
+				{Namespace: "my-ns.servicebus.windows.net", Eventhub: "eh", ConsumerGroup: "table-cg"},
+			},
+		}, nil
+	}
+	return discovery
+}
+
+func blobGroups(endpoint string) blobstorage.StoredGroupsMap {
+	return blobstorage.StoredGroupsMap{
+		{Endpoint: endpoint, Container: "checkpoints"}: {
+			{Namespace: "my-ns.servicebus.windows.net", Eventhub: "eh", ConsumerGroup: "cg"},
+		},
+	}
+}
+
+// countDiscoveries counts the discoveries of the blob storage accounts.
+func countDiscoveries(discovery *checkpointDiscovery) *int {
+	discoveries := 0
+	discoverContainers := discovery.discoverContainers
+	discovery.discoverContainers = func(ctx context.Context, storageAccountCfg config.BlobStorageConfig) (
+		blobstorage.StoredGroupsMap, error) {
+		discoveries++
+		return discoverContainers(ctx, storageAccountCfg)
+	}
+	return &discoveries
+}
+
+func getDiscovered(t *testing.T, discovery *checkpointDiscovery) (blobstorage.StoredGroupsMap,
+	tablestorage.StoredGroupsMap) {
+
+	t.Helper()
+	storedGroups, storedTableGroups, err := discovery.get(context.Background())
+	if err != nil {
+		t.Fatalf("failed to discover checkpoint stores: %v", err)
+	}
+	return storedGroups, storedTableGroups
+}
+
+// discoveryMetrics keeps the errors reported by the discovery.
+type discoveryMetrics struct {
+	metrics.Service
+	stageErrors          map[string]int
+	storageAccountErrors map[string]string
+}
+
+func newDiscoveryMetrics() *discoveryMetrics {
+	return &discoveryMetrics{
+		Service:              metrics.NewDelegateService(),
+		stageErrors:          make(map[string]int),
+		storageAccountErrors: make(map[string]string),
+	}
+}
+
+func (m *discoveryMetrics) RecordStageError(stage string) {
+	m.stageErrors[stage]++
+}
+
+func (m *discoveryMetrics) RecordStorageAccountError(storageAccount, errorClass string) {
+	m.storageAccountErrors[storageAccount] = errorClass
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"regexp"
+	"sync"
 	"syscall"
 	"time"
 
@@ -16,12 +17,23 @@ import (
 	"github.com/deviceinsight/eventhub-metrics/internal/blobstorage"
 	"github.com/deviceinsight/eventhub-metrics/internal/collector"
 	"github.com/deviceinsight/eventhub-metrics/internal/config"
+	"github.com/deviceinsight/eventhub-metrics/internal/eventhub"
+	"github.com/deviceinsight/eventhub-metrics/internal/health"
 	"github.com/deviceinsight/eventhub-metrics/internal/httpserver"
 	"github.com/deviceinsight/eventhub-metrics/internal/metrics"
 	"github.com/deviceinsight/eventhub-metrics/internal/rest"
-	"golang.org/x/sync/errgroup"
+	"github.com/deviceinsight/eventhub-metrics/internal/snapshot"
+	"github.com/deviceinsight/eventhub-metrics/internal/tablestorage"
+	"github.com/deviceinsight/eventhub-metrics/internal/tracing"
 )
 
+// stallGracePeriod is the time in-flight work gets to return after the cycle timeout cancelled it. A cycle which
+// runs longer than its timeout and the grace period is reported as stalled by the health endpoint.
+const stallGracePeriod = time.Minute
+
+// tracingShutdownTimeout limits how long the pending spans are exported on shutdown.
+const tracingShutdownTimeout = 5 * time.Second
+
 var Version string
 var BuildTime string
 var GitCommit string
@@ -96,6 +108,17 @@ func run() int {
 
 	setGoMemLimit()
 
+	configureRequests(cfg.Collector.Requests)
+
+	if cfg.Exporter.Otlp.Enabled && cfg.Exporter.Otlp.Tracing {
+		shutdown, err := tracing.NewProvider(cfg.Exporter.Otlp.BaseURL, cfg.Exporter.Otlp.Protocol, Version)
+		if err != nil {
+			slog.Error("failed to create tracer provider", "error", err)
+			return 1
+		}
+		defer shutdownTracing(shutdown)
+	}
+
 	defer func() {
 		slog.Debug("service stopping")
 	}()
@@ -107,6 +130,10 @@ func run() int {
 	}
 
 	httpServer := httpserver.NewServer(cfg.Server.Address, cfg.Server.ReadTimeout)
+	healthTracker := newHealthTracker(cfg)
+	httpServer.SetHealthCheck(healthTracker.Check)
+	httpServer.Handle("/ready", healthTracker.ReadyHandler())
+	httpServer.Handle("/live", healthTracker.LiveHandler())
 	go httpServer.Run()
 
 	metricExporters, err := buildExporters(cfg, httpServer)
@@ -115,36 +142,35 @@ func run() int {
 		return 1
 	}
 
-	metricsService := metrics.NewDelegateService(metricExporters...)
-	collectorService := collector.NewService(metricsService, cfg.Collector)
+	metricsService := snapshot.NewService(metrics.NewDelegateService(metricExporters...))
+	httpServer.Handle("/api/v1/consumergroups", metricsService)
+	consumerClients := eventhub.NewConsumerClientPool(credential, cfg.Collector.LagTimeConsumerGroup)
+	defer consumerClients.Close()
+
+	collectorService := collector.NewService(metricsService, cfg.Collector, consumerClients)
+	kafkaService := collector.NewKafkaService(metricsService)
+
+	blobClients := blobstorage.NewClientPool(credential)
+	discovery := newCheckpointDiscovery(credential, blobClients, metricsService, cfg)
+	httpServer.Handle("/api/v1/discovery/refresh", discovery)
+
+	app := &application{
+		cfg:              cfg,
+		credential:       credential,
+		metricsService:   metricsService,
+		collectorService: collectorService,
+		kafkaService:     kafkaService,
+		blobClients:      blobClients,
+		discovery:        discovery,
+		healthTracker:    healthTracker,
+	}
 
 	for {
-		start := time.Now()
-		slog.Info("starting metrics collector")
-		metricsService.StartCollectionCycle()
-		err := collectMetrics(credential, cfg, collectorService)
-
-		if err != nil {
-			if errors.Is(err, rest.ErrAuthentication) {
-				slog.Error("authentication error occurred", "error", err)
-				if cfg.Collector.ExitOnAuthenticationError {
-					slog.Error("exiting due to authentication error (exitOnAuthenticationError=true)")
-					return 1
-				}
-				slog.Warn("continuing despite authentication error (exitOnAuthenticationError=false)")
-			} else {
-				slog.Error("metrics collection failed", "error", err)
-			}
-		}
-
-		if err := metricsService.PushMetrics(); err != nil {
-			slog.Error("failed to push metrics", "error", err)
+		if err := app.runCycle(); err != nil {
+			slog.Error("exiting", "error", err)
 			return 1
 		}
 
-		elapsed := time.Since(start)
-
-		slog.Info("metrics collector finished", "elapsed", elapsed.String())
 		if cfg.Collector.Interval == nil {
 			break
 		}
@@ -155,117 +181,370 @@ func run() int {
 	return 0
 }
 
-//nolint:gocognit
-func collectMetrics(credential *azidentity.DefaultAzureCredential, cfg *config.Config,
-	collectorService collector.Service) error {
+// application holds the services which are shared by all collection cycles.
+type application struct {
+	cfg              *config.Config
+	credential       *azidentity.DefaultAzureCredential
+	metricsService   metrics.Service
+	collectorService collector.Service
+	kafkaService     collector.KafkaService
+	blobClients      *blobstorage.ClientPool
+	discovery        *checkpointDiscovery
+	healthTracker    *health.Tracker
+}
+
+// runCycle collects and pushes the metrics once. It returns an error if the application has to exit, which is also
+// the case if the metrics of a single run without collector.interval couldn't be pushed.
+func (a *application) runCycle() error {
+	start := time.Now()
+	slog.Info("starting metrics collector")
+	a.metricsService.StartCollectionCycle()
+	a.healthTracker.CycleStarted()
+
+	spanCtx, span := tracing.Start(context.Background(), "collection cycle")
+	defer span.End()
+
+	ctx, cancel := newCycleContext(spanCtx, a.cfg.Collector.CycleTimeout)
+	err := collectMetrics(ctx, a.credential, a.cfg, a.discovery, a.healthTracker, a.metricsService,
+		a.collectorService, a.kafkaService)
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	cancel()
+	tracing.SetError(span, err)
+
+	if timedOut {
+		slog.Warn("collection cycle exceeded its timeout, publishing partial metrics",
+			"timeout", a.cfg.Collector.CycleTimeout.String())
+	}
+	a.metricsService.RecordCollectionCycle(time.Since(start), err != nil || timedOut)
+	recordClientStats(a.metricsService, a.blobClients)
 
-	ctx := context.Background()
-	storedGroups, err := getCheckpointContainerInfos(ctx, credential, cfg.StorageAccounts)
 	if err != nil {
-		return fmt.Errorf("failed to get checkpoint container infos: %w", err)
+		if errors.Is(err, rest.ErrAuthentication) {
+			slog.Error("authentication error occurred", "error", err)
+			if a.cfg.Collector.ExitOnAuthenticationError {
+				return fmt.Errorf("authentication error (exitOnAuthenticationError=true): %w", err)
+			}
+			slog.Warn("continuing despite authentication error (exitOnAuthenticationError=false)")
+		} else {
+			slog.Error("metrics collection failed partially", "error", err)
+		}
+	}
+
+	_, pushSpan := tracing.Start(spanCtx, "PushMetrics")
+	pushErr := a.metricsService.PushMetrics()
+	tracing.End(pushSpan, pushErr)
+	if err == nil && timedOut {
+		err = fmt.Errorf("collection cycle exceeded its timeout of %s", a.cfg.Collector.CycleTimeout)
+	}
+	if pushErr != nil {
+		pushErr = fmt.Errorf("failed to push metrics: %w", pushErr)
+		err = errors.Join(err, pushErr)
+	}
+	a.healthTracker.CycleFinished(err)
+
+	if pushErr != nil {
+		// without an interval, the exit code is the only way to report that no metrics were delivered
+		if a.cfg.Collector.Interval == nil {
+			return pushErr
+		}
+		// the push error is counted by the push stage and reported with the next cycle
+		slog.Error("failed to push metrics", "error", pushErr)
+	}
+
+	slog.Info("metrics collector finished", "elapsed", time.Since(start).String())
+	return nil
+}
+
+// shutdownTracing exports the pending spans.
+func shutdownTracing(shutdown func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+
+	if err := shutdown(ctx); err != nil {
+		slog.Warn("failed to shut down tracer provider", "error", err)
+	}
+}
+
+func configureRequests(requestCfg config.RequestConfig) {
+	rest.Configure(rest.RetryOptions{
+		MaxRetries:     requestCfg.MaxRetries,
+		InitialBackoff: requestCfg.InitialBackoff,
+		MaxBackoff:     requestCfg.MaxBackoff,
+		Timeout:        requestCfg.Timeout,
+	})
+}
+
+// newHealthTracker returns a tracker which reports a cycle as stalled once it exceeds its timeout and the grace
+// period, and the application as not alive once no cycle was completed for too long.
+func newHealthTracker(cfg *config.Config) *health.Tracker {
+	return health.NewTracker(healthDurations(cfg))
+}
+
+// healthDurations returns after which time a running cycle is stalled and after which time without a completed
+// cycle the application isn't alive. As the next cycle starts an interval after the previous one completed, two
+// cycles may complete up to an interval and the cycle timeout with the grace period apart without being stalled.
+func healthDurations(cfg *config.Config) (stallAfter, liveAfter time.Duration) {
+	if cfg.Collector.CycleTimeout > 0 {
+		stallAfter = cfg.Collector.CycleTimeout + stallGracePeriod
+	}
+	if cfg.Collector.Interval != nil && cfg.Server.LivenessIntervals > 0 {
+		interval := *cfg.Collector.Interval
+		liveAfter = time.Duration(cfg.Server.LivenessIntervals)*interval + stallAfter
+	}
+	return stallAfter, liveAfter
+}
+
+// recordClientStats records the statistics of the clients, which are cumulative since the application started.
+func recordClientStats(metricsService metrics.Service, blobClients *blobstorage.ClientPool) {
+	blobClientStats := blobClients.Stats()
+	metricsService.RecordBlobClientPool(blobClientStats.Clients, blobClientStats.Created, blobClientStats.Reused)
+
+	for endpoint, requestStats := range rest.Stats() {
+		metricsService.RecordManagementRequests(endpoint, requestStats.Retries, requestStats.Throttles)
+	}
+}
+
+// newCycleContext returns the context of a collection cycle, which cancels all outstanding work once the cycle
+// exceeds its timeout. A timeout of 0 disables the deadline.
+func newCycleContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+func collectMetrics(ctx context.Context, credential *azidentity.DefaultAzureCredential, cfg *config.Config,
+	discovery *checkpointDiscovery, healthTracker *health.Tracker, metricsService metrics.Service,
+	collectorService collector.Service, kafkaService collector.KafkaService) error {
+
+	ctx, span := tracing.Start(ctx, "collectMetrics")
+	defer span.End()
+
+	// storage accounts whose checkpoints can't be discovered are skipped, the metrics of the eventhubs and of the
+	// consumer groups in other accounts are still collected.
+	storedGroups, storedTableGroups, discoveryErr := discovery.get(ctx)
+	if discoveryErr != nil {
+		discoveryErr = fmt.Errorf("failed to discover checkpoint stores: %w", discoveryErr)
+	}
+
+	slog.Debug("using concurrency limit", "concurrency", cfg.Collector.Concurrency)
+
+	// all namespaces share the concurrency limit of eventhubs. the eventhubs of a namespace are scheduled as soon as
+	// the namespace is listed, interleaved with the eventhubs of the other namespaces.
+	cycle := &collectionCycle{
+		credential:        credential,
+		blobClients:       discovery.blobClients,
+		storedGroups:      storedGroups,
+		storedTableGroups: storedTableGroups,
+		metricsService:    metricsService,
+		collectorService:  collectorService,
+		kafkaService:      kafkaService,
+		scheduler:         collector.NewScheduler(),
+		namespaceErrs:     make(map[string][]error),
 	}
 
 	for _, namespaceCfg := range cfg.Namespaces {
+		cycle.submit(namespaceCfg.Endpoint, func(ctx context.Context) error {
+			return cycle.processNamespace(ctx, namespaceCfg)
+		})
+	}
+
+	// a failing namespace, eventhub or consumer group doesn't abort the other tasks, so the metrics of all healthy
+	// targets are pushed. the scheduler returns the errors of all failed tasks.
+	err := errors.Join(discoveryErr, cycle.scheduler.Run(ctx, cfg.Collector.Concurrency))
 
-		namespace, eventHubs, err := collectorService.ProcessNamespace(ctx, credential, namespaceCfg.Endpoint)
+	for _, namespaceCfg := range cfg.Namespaces {
+		healthTracker.NamespaceCollected(namespaceCfg.Endpoint, errors.Join(cycle.namespaceErrs[namespaceCfg.Endpoint]...))
+	}
+
+	tracing.SetError(span, err)
+	return err
+}
+
+// collectionCycle holds everything the tasks of a collection cycle share.
+type collectionCycle struct {
+	credential        *azidentity.DefaultAzureCredential
+	blobClients       *blobstorage.ClientPool
+	storedGroups      blobstorage.StoredGroupsMap
+	storedTableGroups tablestorage.StoredGroupsMap
+	metricsService    metrics.Service
+	collectorService  collector.Service
+	kafkaService      collector.KafkaService
+	scheduler         *collector.Scheduler
+
+	namespaceErrsMu sync.Mutex
+	namespaceErrs   map[string][]error
+}
+
+// submit schedules a task of a namespace and keeps its error for the status of the namespace.
+func (c *collectionCycle) submit(endpoint string, task collector.Task) {
+	c.scheduler.Submit(endpoint, func(ctx context.Context) error {
+		err := task(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to process namespace %s: %w", namespace, err)
+			c.namespaceErrsMu.Lock()
+			c.namespaceErrs[endpoint] = append(c.namespaceErrs[endpoint], err)
+			c.namespaceErrsMu.Unlock()
 		}
+		return err
+	})
+}
 
-		includedEventHubsRegex, err := parseRegex(namespaceCfg.IncludedEventHubs)
-		if err != nil {
-			return fmt.Errorf("failed to compile includedEventHubs regex: %w", err)
+// processNamespace lists the eventhubs of a namespace and submits a task for every included eventhub. An eventhub
+// whose checkpoint stores can't be determined is skipped.
+func (c *collectionCycle) processNamespace(ctx context.Context, namespaceCfg config.NamespaceConfig) error {
+	namespace, eventHubs, err := c.collectorService.ProcessNamespace(ctx, c.credential, namespaceCfg.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to process namespace %s: %w", namespace, err)
+	}
+
+	includedEventHubsRegex, err := parseRegex(namespaceCfg.IncludedEventHubs)
+	if err != nil {
+		return fmt.Errorf("failed to compile includedEventHubs regex: %w", err)
+	}
+
+	excludeEventHubsRegex, err := parseRegex(namespaceCfg.ExcludedEventHubs)
+	if err != nil {
+		return fmt.Errorf("failed to compile excludedEventHubs regex: %w", err)
+	}
+
+	excludeConsumerGroupsRegex, err := parseRegex(namespaceCfg.ExcludedConsumerGroups)
+	if err != nil {
+		return fmt.Errorf("failed to compile excludedConsumerGroups regex: %w", err)
+	}
+
+	var errs []error
+
+	for _, eventHub := range eventHubs {
+
+		if includedEventHubsRegex != nil && !includedEventHubsRegex.MatchString(eventHub.Name) {
+			slog.Debug("skipping non-included eventhub", "eventhub", eventHub.Name,
+				"regex", includedEventHubsRegex.String())
+			continue
 		}
 
-		excludeEventHubsRegex, err := parseRegex(namespaceCfg.ExcludedEventHubs)
-		if err != nil {
-			return fmt.Errorf("failed to compile excludedEventHubs regex: %w", err)
+		if excludeEventHubsRegex != nil && excludeEventHubsRegex.MatchString(eventHub.Name) {
+			slog.Debug("skipping excluded eventhub", "eventhub", eventHub.Name,
+				"regex", excludeEventHubsRegex.String())
+			continue
 		}
 
-		excludeConsumerGroupsRegex, err := parseRegex(namespaceCfg.ExcludedConsumerGroups)
+		checkpointStores, err := getCheckpointStores(c.credential, c.blobClients, c.storedGroups,
+			c.storedTableGroups, namespaceCfg, eventHub.Name)
 		if err != nil {
-			return fmt.Errorf("failed to compile excludedConsumerGroups regex: %w", err)
+			slog.Warn("skipping eventhub without checkpoint stores", "namespace", namespace,
+				"eventhub", eventHub.Name, "error", err)
+			c.metricsService.RecordCollectionError(namespace, eventHub.Name, "", collector.ClassifyError(err))
+			errs = append(errs, fmt.Errorf("failed to get checkpoint stores of eventhub %s in namespace %s: %w",
+				eventHub.Name, namespace, err))
+			continue
 		}
 
-		slog.Debug("using concurrency limit", "concurrency", cfg.Collector.Concurrency)
+		c.submit(namespaceCfg.Endpoint, func(ctx context.Context) error {
+			if err := c.collectorService.ProcessEventHub(ctx, c.credential, checkpointStores, namespace,
+				namespaceCfg.Endpoint, &eventHub, excludeConsumerGroupsRegex); err != nil {
+				return fmt.Errorf("failed to process eventhub %s in namespace %s: %w",
+					eventHub.Name, namespace, err)
+			}
+			return nil
+		})
+	}
+
+	if namespaceCfg.Kafka.Enabled {
+		c.submit(namespaceCfg.Endpoint, func(ctx context.Context) error {
+			if err := c.kafkaService.ProcessNamespace(ctx, c.credential, namespaceCfg.Endpoint, includedEventHubsRegex,
+				excludeEventHubsRegex, excludeConsumerGroupsRegex); err != nil {
+				return fmt.Errorf("failed to process kafka consumer groups in namespace %s: %w", namespace, err)
+			}
+			return nil
+		})
+	}
+
+	return errors.Join(errs...)
+}
 
-		g, gCtx := errgroup.WithContext(ctx)
-		g.SetLimit(cfg.Collector.Concurrency)
+// getCheckpointContainerInfos discovers the consumer groups whose checkpoints are stored in the containers of a
+// storage account.
+func getCheckpointContainerInfos(ctx context.Context, blobClients *blobstorage.ClientPool,
+	storageAccountCfg config.BlobStorageConfig) (blobstorage.StoredGroupsMap, error) {
 
-		for _, eventHub := range eventHubs {
+	includedContainersRegex, err := parseRegex(storageAccountCfg.IncludedContainers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile includedContainers regex: %w", err)
+	}
 
-			if includedEventHubsRegex != nil && !includedEventHubsRegex.MatchString(eventHub.Name) {
-				slog.Debug("skipping non-included eventhub", "eventhub", eventHub.Name,
-					"regex", includedEventHubsRegex.String())
-				continue
-			}
+	excludedContainersRegex, err := parseRegex(storageAccountCfg.ExcludedContainers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile excludedContainers regex: %w", err)
+	}
 
-			if excludeEventHubsRegex != nil && excludeEventHubsRegex.MatchString(eventHub.Name) {
-				slog.Debug("skipping excluded eventhub", "eventhub", eventHub.Name,
-					"regex", excludeEventHubsRegex.String())
-				continue
-			}
+	return blobstorage.GetContainerInfos(ctx, blobClients, storageAccountCfg.Endpoint,
+		storageAccountCfg.LegacyNamespace, storageAccountCfg.LegacyPrefix, includedContainersRegex,
+		excludedContainersRegex)
+}
 
-			blobStores, err := blobstorage.GetBlobStores(credential, storedGroups, namespaceCfg.Endpoint, eventHub.Name)
-			if err != nil {
-				// Wait for any in-flight goroutines to finish before returning,
-				// so no goroutine, AMQP/blob client, or context is leaked.
-				_ = g.Wait()
-				return fmt.Errorf("failed to get blob stores for namespace %s: %w", namespace, err)
-			}
+// getCheckpointTableInfos discovers the consumer groups whose checkpoints are stored in the tables of a storage
+// account.
+func getCheckpointTableInfos(ctx context.Context, credential *azidentity.DefaultAzureCredential,
+	storageAccountCfg config.TableStorageConfig) (tablestorage.StoredGroupsMap, error) {
 
-			g.Go(func() error {
-				if err := collectorService.ProcessEventHub(gCtx, credential, blobStores, namespace,
-					namespaceCfg.Endpoint, &eventHub, excludeConsumerGroupsRegex); err != nil {
-					return fmt.Errorf("failed to process eventhub %s in namespace %s: %w",
-						eventHub.Name, namespace, err)
-				}
-				return nil
-			})
-		}
+	includedTablesRegex, err := parseRegex(storageAccountCfg.IncludedTables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile includedTables regex: %w", err)
+	}
 
-		// errgroup cancels gCtx on first error (propagated through Azure SDK / blob
-		// calls so in-flight work aborts promptly) and waits for every goroutine to
-		// return — guarantees no goroutine leak even on the error path.
-		if err := g.Wait(); err != nil {
-			return err
-		}
+	excludedTablesRegex, err := parseRegex(storageAccountCfg.ExcludedTables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile excludedTables regex: %w", err)
 	}
 
-	return nil
+	return tablestorage.GetTableInfos(ctx, credential, storageAccountCfg.Endpoint, includedTablesRegex,
+		excludedTablesRegex)
 }
 
-func getCheckpointContainerInfos(ctx context.Context, credential *azidentity.DefaultAzureCredential,
-	storageAccounts []config.BlobStorageConfig) (blobstorage.StoredGroupsMap, error) {
+// getCheckpointStores returns the checkpoint stores of all consumer groups of an eventhub. Explicitly configured
+// checkpoint stores take precedence over discovered blob containers, which take precedence over tables.
+func getCheckpointStores(credential *azidentity.DefaultAzureCredential, blobClients *blobstorage.ClientPool,
+	storedGroups blobstorage.StoredGroupsMap, storedTableGroups tablestorage.StoredGroupsMap,
+	namespaceCfg config.NamespaceConfig, eventHub string) (map[string]eventhub.CheckpointStore, error) {
 
-	containerInfos := make(blobstorage.StoredGroupsMap)
+	namespace := namespaceCfg.Endpoint
 
-	for _, storageAccountCfg := range storageAccounts {
+	checkpointStores, err := blobClients.GetBlobStores(storedGroups, namespace, eventHub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob stores: %w", err)
+	}
 
-		includedContainersRegex, err := parseRegex(storageAccountCfg.IncludedContainers)
-		if err != nil {
-			slog.Error("failed to compile includedContainers regex", "error", err)
-			return nil, err
+	tableStores, err := tablestorage.GetCheckpointStores(credential, storedTableGroups, namespace, eventHub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table stores: %w", err)
+	}
+
+	for consumerGroup, tableStore := range tableStores {
+		if _, ok := checkpointStores[consumerGroup]; !ok {
+			checkpointStores[consumerGroup] = tableStore
 		}
+	}
 
-		excludedContainersRegex, err := parseRegex(storageAccountCfg.ExcludedContainers)
-		if err != nil {
-			slog.Error("failed to compile excludedContainers regex", "error", err)
-			return nil, err
+	for _, checkpointStoreCfg := range namespaceCfg.CheckpointStores {
+		if checkpointStoreCfg.EventHub != eventHub {
+			continue
 		}
 
-		infos, err := blobstorage.GetContainerInfos(ctx, credential, storageAccountCfg.Endpoint,
-			includedContainersRegex, excludedContainersRegex)
+		checkpointStore, err := blobClients.GetBlobStore(blobstorage.StorageContainer{
+			Endpoint:  checkpointStoreCfg.Endpoint,
+			Container: checkpointStoreCfg.Container,
+			Prefix:    checkpointStoreCfg.Prefix,
+			Legacy:    checkpointStoreCfg.Legacy,
+		})
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to get configured checkpoint store of consumerGroup %s: %w",
+				checkpointStoreCfg.ConsumerGroup, err)
 		}
 
-		for storageContainer, storedConsumerGroups := range infos {
-			containerInfos[storageContainer] = storedConsumerGroups
-		}
+		checkpointStores[checkpointStoreCfg.ConsumerGroup] = checkpointStore
 	}
 
-	return containerInfos, nil
+	return checkpointStores, nil
 }
 
 func parseRegex(regexString string) (*regexp.Regexp, error) {
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deviceinsight/eventhub-metrics/internal/blobstorage"
+	"github.com/deviceinsight/eventhub-metrics/internal/config"
+	"github.com/deviceinsight/eventhub-metrics/internal/health"
+	"github.com/deviceinsight/eventhub-metrics/internal/metrics"
+	"github.com/deviceinsight/eventhub-metrics/internal/tablestorage"
+)
+
+func TestGetCheckpointStoresPrefersConfiguredStores(t *testing.T) {
+	const namespace = "my-ns.servicebus.windows.net"
+
+	blobClients := blobstorage.NewClientPool(nil)
+	discovered := blobstorage.StorageContainer{Endpoint: "discovered.blob.core.windows.net", Container: "checkpoints"}
+	storedGroups := blobstorage.StoredGroupsMap{
+		discovered: {
+			{Namespace: namespace, Eventhub: "eh", ConsumerGroup: "blob-cg"},
+			{Namespace: namespace, Eventhub: "eh", ConsumerGroup: "discovered-cg"},
+		},
+	}
+	storedTableGroups := tablestorage.StoredGroupsMap{
+		{Endpoint: "discovered.table.core.windows.net", Table: "checkpoints"}: {
+			{Namespace: namespace, Eventhub: "eh", ConsumerGroup: "table-cg"},
+		},
+	}
+
+	configured := blobstorage.StorageContainer{Endpoint: "configured.blob.core.windows.net", Container: "processors",
+		Prefix: "processor-1"}
+	namespaceCfg := config.NamespaceConfig{
+		Endpoint: namespace,
+		CheckpointStores: []config.CheckpointStoreConfig{
+			{EventHub: "eh", ConsumerGroup: "blob-cg", Endpoint: configured.Endpoint, Container: configured.Container,
+				Prefix: configured.Prefix},
+			{EventHub: "eh", ConsumerGroup: "table-cg", Endpoint: configured.Endpoint, Container: configured.Container,
+				Prefix: configured.Prefix},
+			{EventHub: "other", ConsumerGroup: "discovered-cg", Endpoint: configured.Endpoint,
+				Container: configured.Container},
+		},
+	}
+
+	checkpointStores, err := getCheckpointStores(nil, blobClients, storedGroups, storedTableGroups, namespaceCfg,
+		"eh")
+	if err != nil {
+		t.Fatalf("failed to get checkpoint stores: %v", err)
+	}
+
+	// the pool returns the same store for the same container and prefix
+	configuredStore, err := blobClients.GetBlobStore(configured)
+	if err != nil {
+		t.Fatalf("failed to get configured store: %v", err)
+	}
+	discoveredStore, err := blobClients.GetBlobStore(discovered)
+	if err != nil {
+		t.Fatalf("failed to get discovered store: %v", err)
+	}
+
+	if checkpointStores["blob-cg"] != configuredStore {
+		t.Error("expected the configured store to override the discovered container")
+	}
+	if checkpointStores["table-cg"] != configuredStore {
+		t.Error("expected the configured store to override the discovered table")
+	}
+	if checkpointStores["discovered-cg"] != discoveredStore {
+		t.Error("expected the store of another eventhub not to override the discovered container")
+	}
+}
+
+func TestHealthDurationsIncludeCycleTimeout(t *testing.T) {
+	interval := 5 * time.Minute
+	cfg := &config.Config{
+		Collector: config.CollectorConfig{Interval: &interval, CycleTimeout: 10 * time.Minute},
+		Server:    config.ServerConfig{LivenessIntervals: 3},
+	}
+
+	stallAfter, liveAfter := healthDurations(cfg)
+	if stallAfter != 11*time.Minute {
+		t.Errorf("expected a cycle to stall after 11m, got %s", stallAfter)
+	}
+
+	// 3 intervals in addition to a cycle which runs until it stalls
+	if liveAfter != 26*time.Minute {
+		t.Errorf("expected live to fail after 26m, got %s", liveAfter)
+	}
+
+	cfg.Collector.CycleTimeout = 0
+	if stallAfter, liveAfter := healthDurations(cfg); stallAfter != 0 || liveAfter != 15*time.Minute {
+		t.Errorf("expected no stall detection and live to fail after 15m, got %s and %s", stallAfter, liveAfter)
+	}
+}
+
+func TestRunCycleReturnsPushErrorOnlyWithoutInterval(t *testing.T) {
+	cfg := &config.Config{}
+	metricsService := failingPushService{metrics.NewDelegateService()}
+	blobClients := blobstorage.NewClientPool(nil)
+	app := &application{
+		cfg:            cfg,
+		metricsService: metricsService,
+		blobClients:    blobClients,
+		discovery:      newCheckpointDiscovery(nil, blobClients, metricsService, cfg),
+		healthTracker:  health.NewTracker(0, 0),
+	}
+
+	if err := app.runCycle(); err == nil {
+		t.Fatal("expected a failed push to exit a single run")
+	}
+
+	interval := time.Minute
+	cfg.Collector.Interval = &interval
+	if err := app.runCycle(); err != nil {
+		t.Fatalf("expected a failed push not to exit the interval mode, got %v", err)
+	}
+	if status, _ := app.healthTracker.Live(); status.LastError == "" {
+		t.Fatal("expected the failed push to be reported by the health status")
+	}
+}
+
+// failingPushService fails to push the metrics.
+type failingPushService struct {
+	metrics.Service
+}
+
+func (failingPushService) PushMetrics() error {
+	return errors.New("unavailable")
+}
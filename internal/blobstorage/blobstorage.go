@@ -15,8 +15,6 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
-	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2/checkpoints"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/deviceinsight/eventhub-metrics/internal/rest"
 )
@@ -27,8 +25,14 @@ const MinDirectoryDepth = 3
 type StorageContainer struct {
 	Endpoint  string
 	Container string
+	// Prefix of the blob names, e.g. for processors which don't store their checkpoints at the container root.
+	Prefix string
+	// Legacy is set for containers with the lease blobs of the legacy EventProcessorHost.
+	Legacy bool
 }
 
+// StoredConsumerGroup is a consumer group found in a storage container. The eventhub of a legacy container may differ
+// in case from the actual name, since container names are lowercase.
 type StoredConsumerGroup struct {
 	Namespace     string
 	Eventhub      string
@@ -37,10 +41,13 @@ type StoredConsumerGroup struct {
 
 type StoredGroupsMap = map[StorageContainer][]StoredConsumerGroup
 
-func GetContainerInfos(ctx context.Context, credential *azidentity.DefaultAzureCredential, endpoint string,
+// GetContainerInfos discovers the consumer groups whose checkpoints are stored in the containers of a storage account.
+// If legacyNamespace is set, containers with the layout of the legacy EventProcessorHost below legacyPrefix are
+// attributed to the eventhub of the same name in that namespace.
+func GetContainerInfos(ctx context.Context, pool *ClientPool, endpoint, legacyNamespace, legacyPrefix string,
 	includedContainersRegex, excludedContainersRegex *regexp.Regexp) (StoredGroupsMap, error) {
 
-	blobClient, err := getBlobClient(credential, endpoint)
+	blobClient, err := pool.getBlobClient(endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -81,8 +88,36 @@ func GetContainerInfos(ctx context.Context, credential *azidentity.DefaultAzureC
 				Endpoint:  endpoint,
 				Container: containerName,
 			}] = storedConsumerGroups
+			continue
 		}
 
+		if legacyNamespace == "" {
+			continue
+		}
+
+		legacyConsumerGroups, err := listLegacyConsumerGroups(ctx, containerClient, legacyPrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, consumerGroup := range legacyConsumerGroups {
+			storedConsumerGroups = append(storedConsumerGroups, StoredConsumerGroup{
+				Namespace:     legacyNamespace,
+				Eventhub:      containerName,
+				ConsumerGroup: consumerGroup,
+			})
+		}
+
+		if len(storedConsumerGroups) > 0 {
+			slog.Debug("found legacy EventProcessorHost container", "endpoint", endpoint,
+				"container", containerName)
+			infos[StorageContainer{
+				Endpoint:  endpoint,
+				Container: containerName,
+				Prefix:    legacyPrefix,
+				Legacy:    true,
+			}] = storedConsumerGroups
+		}
 	}
 
 	return infos, nil
@@ -161,68 +196,6 @@ func getContainers(ctx context.Context, client *azblob.Client,
 	return containers, nil
 }
 
-func GetBlobStores(credential *azidentity.DefaultAzureCredential, storedGroupsMap StoredGroupsMap,
-	namespace, eventHub string) (map[string]*checkpoints.BlobStore, error) {
-
-	consumerGroupBlobStores := make(map[string]*checkpoints.BlobStore)
-
-	for storageContainer, storedConsumerGroups := range storedGroupsMap {
-
-		consumerGroups := make([]string, 0)
-
-		for _, storedConsumerGroup := range storedConsumerGroups {
-			if storedConsumerGroup.Namespace == namespace && storedConsumerGroup.Eventhub == eventHub {
-				consumerGroups = append(consumerGroups, storedConsumerGroup.ConsumerGroup)
-			}
-		}
-
-		if len(consumerGroups) > 0 {
-			blobStore, err := getBlobStore(credential, storageContainer)
-			if err != nil {
-				return nil, fmt.Errorf("unable to create blob store for endpoint=%s, error=%w",
-					storageContainer.Endpoint, err)
-			}
-
-			for _, consumerGroup := range consumerGroups {
-				consumerGroupBlobStores[consumerGroup] = blobStore
-			}
-		}
-	}
-
-	return consumerGroupBlobStores, nil
-}
-
-func getBlobStore(credential *azidentity.DefaultAzureCredential,
-	storageContainer StorageContainer) (*checkpoints.BlobStore, error) {
-
-	storageServiceURL, err := getStorageServiceURL(storageContainer.Endpoint)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse storage service url: %w", err)
-	}
-
-	blobClient, err := azblob.NewClient(storageServiceURL.String(), credential, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating blob client: %w", err)
-	}
-
-	azBlobContainerClient := blobClient.ServiceClient().NewContainerClient(storageContainer.Container)
-
-	return checkpoints.NewBlobStore(azBlobContainerClient, nil)
-}
-
-func getBlobClient(credential *azidentity.DefaultAzureCredential, endpoint string) (*azblob.Client, error) {
-	storageServiceURL, err := getStorageServiceURL(endpoint)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse storage service url: %w", err)
-	}
-
-	blobClient, err := azblob.NewClient(storageServiceURL.String(), credential, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating blob client: %w", err)
-	}
-	return blobClient, nil
-}
-
 func getStorageServiceURL(endpoint string, paths ...string) (*url.URL, error) {
 	u, err := url.Parse(fmt.Sprintf("https://%s", endpoint))
 	if err != nil {
@@ -0,0 +1,176 @@
+package blobstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/deviceinsight/eventhub-metrics/internal/eventhub"
+	"github.com/deviceinsight/eventhub-metrics/internal/tracing"
+)
+
+// CheckpointStore reads the checkpoints and ownerships an Event Hubs processor keeps in a storage container.
+// It implements eventhub.CheckpointStore and uses the blob layout of checkpoints.BlobStore below an optional prefix.
+type CheckpointStore struct {
+	containerClient *container.Client
+	prefix          string
+}
+
+func newCheckpointStore(containerClient *container.Client, prefix string) *CheckpointStore {
+	return &CheckpointStore{containerClient: containerClient, prefix: normalizePrefix(prefix)}
+}
+
+// ListCheckpoints lists the checkpoints of a consumer group. Unlike checkpoints.BlobStore it also returns when each
+// checkpoint blob was last modified.
+func (s *CheckpointStore) ListCheckpoints(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]eventhub.Checkpoint, error) {
+
+	ctx, span := tracing.Start(ctx, "blobstorage.ListCheckpoints",
+		tracing.AttributeContainer.String(s.containerClient.URL()), tracing.AttributeConsumerGroup.String(consumerGroup))
+	checkpoints, err := s.listCheckpoints(ctx, namespace, eventHub, consumerGroup)
+	tracing.End(span, err)
+	return checkpoints, err
+}
+
+func (s *CheckpointStore) listCheckpoints(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]eventhub.Checkpoint, error) {
+
+	prefix := s.blobPrefix(namespace, eventHub, consumerGroup, "checkpoint")
+
+	pager := s.containerClient.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Prefix: &prefix,
+		Include: container.ListBlobsInclude{
+			Metadata: true,
+		},
+	})
+
+	var checkpointList []eventhub.Checkpoint
+
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, blob := range resp.Segment.BlobItems {
+			checkpoint := eventhub.Checkpoint{
+				Checkpoint: azeventhubs.Checkpoint{
+					FullyQualifiedNamespace: namespace,
+					EventHubName:            eventHub,
+					ConsumerGroup:           consumerGroup,
+					PartitionID:             path.Base(*blob.Name),
+				},
+			}
+
+			if err := parseCheckpointMetadata(blob.Metadata, &checkpoint); err != nil {
+				return nil, fmt.Errorf("invalid checkpoint blob %s: %w", *blob.Name, err)
+			}
+
+			if blob.Properties != nil {
+				checkpoint.LastModified = blob.Properties.LastModified
+			}
+
+			checkpointList = append(checkpointList, checkpoint)
+		}
+	}
+
+	return checkpointList, nil
+}
+
+// ListOwnership lists the ownerships of a consumer group like checkpoints.BlobStore.
+func (s *CheckpointStore) ListOwnership(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]azeventhubs.Ownership, error) {
+
+	ctx, span := tracing.Start(ctx, "blobstorage.ListOwnership",
+		tracing.AttributeContainer.String(s.containerClient.URL()), tracing.AttributeConsumerGroup.String(consumerGroup))
+	ownerships, err := s.listOwnership(ctx, namespace, eventHub, consumerGroup)
+	tracing.End(span, err)
+	return ownerships, err
+}
+
+func (s *CheckpointStore) listOwnership(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]azeventhubs.Ownership, error) {
+
+	prefix := s.blobPrefix(namespace, eventHub, consumerGroup, "ownership")
+
+	pager := s.containerClient.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Prefix: &prefix,
+		Include: container.ListBlobsInclude{
+			Metadata: true,
+		},
+	})
+
+	var ownerships []azeventhubs.Ownership
+
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, blob := range resp.Segment.BlobItems {
+			if blob.Properties == nil || blob.Properties.LastModified == nil {
+				return nil, fmt.Errorf("invalid ownership blob %s: properties are missing", *blob.Name)
+			}
+
+			ownership := azeventhubs.Ownership{
+				FullyQualifiedNamespace: namespace,
+				EventHubName:            eventHub,
+				ConsumerGroup:           consumerGroup,
+				PartitionID:             path.Base(*blob.Name),
+				LastModifiedTime:        *blob.Properties.LastModified,
+				ETag:                    blob.Properties.ETag,
+			}
+
+			// a relinquished ownership has no ownerid, which azblob omits from the metadata
+			if ownerID, ok := blob.Metadata["ownerid"]; ok && ownerID != nil {
+				ownership.OwnerID = *ownerID
+			}
+
+			ownerships = append(ownerships, ownership)
+		}
+	}
+
+	return ownerships, nil
+}
+
+// blobPrefix returns the directory of the checkpoint or ownership blobs of a consumer group:
+// prefix/fully-qualified-namespace/event-hub-name/consumer-group/kind/partition-id
+func (s *CheckpointStore) blobPrefix(namespace, eventHub, consumerGroup, kind string) string {
+	return fmt.Sprintf("%s%s/%s/%s/%s/", s.prefix, namespace, eventHub, consumerGroup, kind)
+}
+
+// normalizePrefix makes sure a non-empty prefix ends with a slash.
+func normalizePrefix(prefix string) string {
+	if prefix == "" || strings.HasSuffix(prefix, "/") {
+		return prefix
+	}
+	return prefix + "/"
+}
+
+// parseCheckpointMetadata reads the metadata written by checkpoints.BlobStore.SetCheckpoint.
+func parseCheckpointMetadata(metadata map[string]*string, checkpoint *eventhub.Checkpoint) error {
+	sequenceNumberStr, ok := metadata["sequencenumber"]
+	if !ok || sequenceNumberStr == nil {
+		return errors.New("sequencenumber is missing from metadata")
+	}
+
+	sequenceNumber, err := strconv.ParseInt(*sequenceNumberStr, 10, 64)
+	if err != nil {
+		return fmt.Errorf("sequencenumber could not be parsed as an int64: %w", err)
+	}
+
+	offset, ok := metadata["offset"]
+	if !ok || offset == nil {
+		return errors.New("offset is missing from metadata")
+	}
+
+	checkpoint.SequenceNumber = &sequenceNumber
+	checkpoint.Offset = offset
+	return nil
+}
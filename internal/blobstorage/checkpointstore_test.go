@@ -0,0 +1,22 @@
+package blobstorage
+
+import "testing"
+
+func TestCheckpointStoreBlobPrefix(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{prefix: "", expected: "ns.servicebus.windows.net/eh/cg/checkpoint/"},
+		{prefix: "processor-1", expected: "processor-1/ns.servicebus.windows.net/eh/cg/checkpoint/"},
+		{prefix: "processor-1/", expected: "processor-1/ns.servicebus.windows.net/eh/cg/checkpoint/"},
+	}
+
+	for _, test := range tests {
+		store := newCheckpointStore(nil, test.prefix)
+		blobPrefix := store.blobPrefix("ns.servicebus.windows.net", "eh", "cg", "checkpoint")
+		if blobPrefix != test.expected {
+			t.Errorf("expected %s for prefix %q, got %s", test.expected, test.prefix, blobPrefix)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package blobstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/lease"
+	"github.com/deviceinsight/eventhub-metrics/internal/eventhub"
+	"github.com/deviceinsight/eventhub-metrics/internal/tracing"
+)
+
+// legacyListingMaxAge is how long the leases downloaded for the checkpoints are reused for the ownerships.
+const legacyListingMaxAge = 30 * time.Second
+
+// LegacyCheckpointStore reads the leases of the EventProcessorHost of the legacy Event Hubs SDKs. It implements
+// eventhub.CheckpointStore.
+//
+// The EventProcessorHost keeps one JSON blob per partition at consumer-group/partition-id, which contains both the
+// checkpoint and the owner of the partition. The blobs contain neither the namespace nor the eventhub, so every
+// container belongs to a single eventhub. The blobs may be stored below a prefix (storageBlobPrefix).
+//
+// Since every lease blob has to be downloaded, the leases downloaded by ListCheckpoints are reused by the following
+// ListOwnership of the same consumer group.
+type LegacyCheckpointStore struct {
+	containerClient *container.Client
+	prefix          string
+	downloadLeases  func(ctx context.Context, consumerGroup string) ([]legacyLeaseBlob, error)
+	now             func() time.Time
+
+	mu       sync.Mutex
+	listings map[string]legacyListing
+}
+
+type legacyListing struct {
+	leaseBlobs []legacyLeaseBlob
+	listedAt   time.Time
+}
+
+// legacyLease is the content of a lease blob. .NET and Java serialize the fields in different cases.
+type legacyLease struct {
+	PartitionID    string `json:"PartitionId"`
+	Owner          string
+	Epoch          int64
+	Offset         *string
+	SequenceNumber int64
+}
+
+type legacyLeaseBlob struct {
+	lease        legacyLease
+	lastModified *time.Time
+	leased       bool
+}
+
+func newLegacyCheckpointStore(containerClient *container.Client, prefix string) *LegacyCheckpointStore {
+	s := &LegacyCheckpointStore{
+		containerClient: containerClient,
+		prefix:          normalizePrefix(prefix),
+		now:             time.Now,
+		listings:        make(map[string]legacyListing),
+	}
+	s.downloadLeases = s.download
+	return s
+}
+
+// ListCheckpoints returns the checkpoints of all partitions which have been checkpointed at least once.
+func (s *LegacyCheckpointStore) ListCheckpoints(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]eventhub.Checkpoint, error) {
+
+	ctx, span := tracing.Start(ctx, "blobstorage.legacy.ListCheckpoints",
+		tracing.AttributeContainer.String(s.containerClient.URL()), tracing.AttributeConsumerGroup.String(consumerGroup))
+	checkpoints, err := s.listCheckpoints(ctx, namespace, eventHub, consumerGroup)
+	tracing.End(span, err)
+	return checkpoints, err
+}
+
+func (s *LegacyCheckpointStore) listCheckpoints(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]eventhub.Checkpoint, error) {
+
+	leaseBlobs, err := s.downloadLeases(ctx, consumerGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.listings[consumerGroup] = legacyListing{leaseBlobs: leaseBlobs, listedAt: s.now()}
+	s.mu.Unlock()
+
+	return getLegacyCheckpoints(leaseBlobs, namespace, eventHub, consumerGroup), nil
+}
+
+// getLegacyCheckpoints returns the checkpoints of all partitions which have been checkpointed at least once.
+func getLegacyCheckpoints(leaseBlobs []legacyLeaseBlob, namespace, eventHub,
+	consumerGroup string) []eventhub.Checkpoint {
+
+	var checkpointList []eventhub.Checkpoint
+
+	for _, leaseBlob := range leaseBlobs {
+		// the offset of a lease is only set once the partition is checkpointed
+		if leaseBlob.lease.Offset == nil {
+			continue
+		}
+
+		checkpointList = append(checkpointList, eventhub.Checkpoint{
+			Checkpoint: azeventhubs.Checkpoint{
+				FullyQualifiedNamespace: namespace,
+				EventHubName:            eventHub,
+				ConsumerGroup:           consumerGroup,
+				PartitionID:             leaseBlob.lease.PartitionID,
+				Offset:                  leaseBlob.lease.Offset,
+				SequenceNumber:          to.Ptr(leaseBlob.lease.SequenceNumber),
+			},
+			LastModified: leaseBlob.lastModified,
+		})
+	}
+
+	return checkpointList
+}
+
+// ListOwnership derives the ownerships from the blob leases. The EventProcessorHost renews a blob lease without
+// modifying the blob, so partitions with an active lease are reported as owned at the time of the listing.
+func (s *LegacyCheckpointStore) ListOwnership(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]azeventhubs.Ownership, error) {
+
+	ctx, span := tracing.Start(ctx, "blobstorage.legacy.ListOwnership",
+		tracing.AttributeContainer.String(s.containerClient.URL()), tracing.AttributeConsumerGroup.String(consumerGroup))
+	ownerships, err := s.listOwnership(ctx, namespace, eventHub, consumerGroup)
+	tracing.End(span, err)
+	return ownerships, err
+}
+
+func (s *LegacyCheckpointStore) listOwnership(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]azeventhubs.Ownership, error) {
+
+	s.mu.Lock()
+	listing, ok := s.listings[consumerGroup]
+	delete(s.listings, consumerGroup)
+	s.mu.Unlock()
+
+	if !ok || s.now().Sub(listing.listedAt) > legacyListingMaxAge {
+		leaseBlobs, err := s.downloadLeases(ctx, consumerGroup)
+		if err != nil {
+			return nil, err
+		}
+		listing = legacyListing{leaseBlobs: leaseBlobs, listedAt: s.now()}
+	}
+
+	return getLegacyOwnerships(listing, namespace, eventHub, consumerGroup), nil
+}
+
+// getLegacyOwnerships derives the ownerships from the leases of the blobs. A leased partition is reported as owned at
+// the time of the listing, since the lease is renewed without modifying the blob.
+func getLegacyOwnerships(listing legacyListing, namespace, eventHub, consumerGroup string) []azeventhubs.Ownership {
+	var ownerships []azeventhubs.Ownership
+
+	for _, leaseBlob := range listing.leaseBlobs {
+		ownership := azeventhubs.Ownership{
+			FullyQualifiedNamespace: namespace,
+			EventHubName:            eventHub,
+			ConsumerGroup:           consumerGroup,
+			PartitionID:             leaseBlob.lease.PartitionID,
+		}
+
+		if leaseBlob.leased {
+			ownership.OwnerID = leaseBlob.lease.Owner
+			ownership.LastModifiedTime = listing.listedAt
+		} else if leaseBlob.lastModified != nil {
+			ownership.LastModifiedTime = *leaseBlob.lastModified
+		}
+
+		ownerships = append(ownerships, ownership)
+	}
+
+	return ownerships
+}
+
+// download lists and downloads the lease blobs of a consumer group.
+func (s *LegacyCheckpointStore) download(ctx context.Context, consumerGroup string) ([]legacyLeaseBlob, error) {
+	prefix := s.prefix + consumerGroup + "/"
+
+	pager := s.containerClient.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Prefix: &prefix,
+	})
+
+	var leaseBlobs []legacyLeaseBlob
+
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, blob := range resp.Segment.BlobItems {
+			leaseBlob, err := s.readLease(ctx, *blob.Name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid lease blob %s: %w", *blob.Name, err)
+			}
+
+			if blob.Properties != nil {
+				leaseBlob.lastModified = blob.Properties.LastModified
+				leaseBlob.leased = blob.Properties.LeaseState != nil &&
+					*blob.Properties.LeaseState == lease.StateTypeLeased
+			}
+
+			leaseBlobs = append(leaseBlobs, leaseBlob)
+		}
+	}
+
+	return leaseBlobs, nil
+}
+
+func (s *LegacyCheckpointStore) readLease(ctx context.Context, blobName string) (legacyLeaseBlob, error) {
+	resp, err := s.containerClient.NewBlobClient(blobName).DownloadStream(ctx, nil)
+	if err != nil {
+		return legacyLeaseBlob{}, fmt.Errorf("failed to download blob: %w", err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return legacyLeaseBlob{}, fmt.Errorf("failed to read blob: %w", err)
+	}
+
+	parsedLease, err := parseLegacyLease(blobName, content)
+	if err != nil {
+		return legacyLeaseBlob{}, err
+	}
+	return legacyLeaseBlob{lease: parsedLease}, nil
+}
+
+// parseLegacyLease parses the content of a lease blob. The partition id is taken from the blob name if the lease
+// doesn't contain it.
+func parseLegacyLease(blobName string, content []byte) (legacyLease, error) {
+	var parsedLease legacyLease
+	if err := json.Unmarshal(content, &parsedLease); err != nil {
+		return legacyLease{}, fmt.Errorf("failed to parse lease: %w", err)
+	}
+
+	if parsedLease.PartitionID == "" {
+		parsedLease.PartitionID = path.Base(blobName)
+	}
+
+	return parsedLease, nil
+}
+
+// listLegacyConsumerGroups returns the consumer groups of a container with the layout consumer-group/partition-id
+// below the given prefix.
+func listLegacyConsumerGroups(ctx context.Context, client *container.Client, prefix string) ([]string, error) {
+	prefix = normalizePrefix(prefix)
+	pager := client.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{
+		Prefix: to.Ptr(prefix),
+	})
+
+	var consumerGroups []string
+
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list blobs: %w", err)
+		}
+
+		for _, blobPrefix := range resp.Segment.BlobPrefixes {
+			hasLeases, err := containsBlobs(ctx, client, *blobPrefix.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			if hasLeases {
+				consumerGroups = append(consumerGroups,
+					strings.TrimSuffix(strings.TrimPrefix(*blobPrefix.Name, prefix), "/"))
+			}
+		}
+	}
+
+	return consumerGroups, nil
+}
+
+// containsBlobs reports whether a directory directly contains blobs instead of further directories.
+func containsBlobs(ctx context.Context, client *container.Client, prefix string) (bool, error) {
+	pager := client.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{
+		Prefix:     to.Ptr(prefix),
+		MaxResults: to.Ptr(int32(1)),
+	})
+
+	resp, err := pager.NextPage(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to list blobs: %w", err)
+	}
+
+	return len(resp.Segment.BlobItems) > 0, nil
+}
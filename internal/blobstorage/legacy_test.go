@@ -0,0 +1,169 @@
+package blobstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+func TestParseLegacyLease(t *testing.T) {
+	tests := []struct {
+		name     string
+		blobName string
+		content  string
+		expected legacyLease
+	}{
+		{
+			name:     ".NET",
+			blobName: "cg/3",
+			content:  `{"PartitionId":"3","Owner":"host-1","Token":"abc","Epoch":2,"Offset":"1024","SequenceNumber":57}`,
+			expected: legacyLease{PartitionID: "3", Owner: "host-1", Epoch: 2, Offset: to.Ptr("1024"), SequenceNumber: 57},
+		},
+		{
+			name:     "Java",
+			blobName: "cg/1",
+			content:  `{"partitionId":"1","owner":"host-2","epoch":1,"offset":"512","sequenceNumber":12}`,
+			expected: legacyLease{PartitionID: "1", Owner: "host-2", Epoch: 1, Offset: to.Ptr("512"), SequenceNumber: 12},
+		},
+		{
+			name:     "without checkpoint and partition id",
+			blobName: "prefix/cg/7",
+			content:  `{"Owner":"","Epoch":0}`,
+			expected: legacyLease{PartitionID: "7"},
+		},
+	}
+
+	for _, test := range tests {
+		parsedLease, err := parseLegacyLease(test.blobName, []byte(test.content))
+		if err != nil {
+			t.Fatalf("%s: failed to parse lease: %v", test.name, err)
+		}
+
+		if parsedLease.PartitionID != test.expected.PartitionID || parsedLease.Owner != test.expected.Owner ||
+			parsedLease.Epoch != test.expected.Epoch || parsedLease.SequenceNumber != test.expected.SequenceNumber ||
+			(parsedLease.Offset == nil) != (test.expected.Offset == nil) ||
+			(parsedLease.Offset != nil && *parsedLease.Offset != *test.expected.Offset) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, parsedLease)
+		}
+	}
+
+	if _, err := parseLegacyLease("cg/0", []byte("not json")); err == nil {
+		t.Error("expected an invalid lease to fail")
+	}
+}
+
+func TestGetLegacyOwnerships(t *testing.T) {
+	listedAt := time.Now()
+	released := listedAt.Add(-time.Hour)
+
+	listing := legacyListing{listedAt: listedAt, leaseBlobs: []legacyLeaseBlob{
+		{lease: legacyLease{PartitionID: "0", Owner: "host-1"}, leased: true, lastModified: &released},
+		// the lease expired, but the blob still names its last owner
+		{lease: legacyLease{PartitionID: "1", Owner: "host-2"}, lastModified: &released},
+	}}
+
+	ownerships := getLegacyOwnerships(listing, "ns", "eh", "cg")
+
+	if len(ownerships) != 2 {
+		t.Fatalf("expected 2 ownerships, got %+v", ownerships)
+	}
+	if ownerships[0].OwnerID != "host-1" || !ownerships[0].LastModifiedTime.Equal(listedAt) {
+		t.Errorf("expected a leased partition to be owned at the time of the listing, got %+v", ownerships[0])
+	}
+	if ownerships[1].OwnerID != "" || !ownerships[1].LastModifiedTime.Equal(released) {
+		t.Errorf("expected a partition without lease to have no owner, got %+v", ownerships[1])
+	}
+}
+
+func TestGetLegacyCheckpoints(t *testing.T) {
+	leaseBlobs := []legacyLeaseBlob{
+		{lease: legacyLease{PartitionID: "0", Offset: to.Ptr("1024"), SequenceNumber: 57}},
+		{lease: legacyLease{PartitionID: "1"}},
+	}
+
+	checkpoints := getLegacyCheckpoints(leaseBlobs, "ns", "eh", "cg")
+
+	if len(checkpoints) != 1 || checkpoints[0].PartitionID != "0" || *checkpoints[0].SequenceNumber != 57 {
+		t.Fatalf("expected only the checkpointed partition, got %+v", checkpoints)
+	}
+}
+
+func TestLegacyCheckpointStoreSharesDownloads(t *testing.T) {
+	containerClient, err := container.NewClientWithNoCredential("https://account.blob.core.windows.net/eh", nil)
+	if err != nil {
+		t.Fatalf("failed to create container client: %v", err)
+	}
+
+	now := time.Now()
+	store := newLegacyCheckpointStore(containerClient, "")
+	store.now = func() time.Time { return now }
+
+	downloads := 0
+	store.downloadLeases = func(context.Context, string) ([]legacyLeaseBlob, error) {
+		downloads++
+		return []legacyLeaseBlob{{lease: legacyLease{PartitionID: "0", Owner: "host-1"}, leased: true}}, nil
+	}
+
+	ctx := context.Background()
+	if _, err := store.ListCheckpoints(ctx, "ns", "eh", "cg"); err != nil {
+		t.Fatalf("failed to list checkpoints: %v", err)
+	}
+	if _, err := store.ListOwnership(ctx, "ns", "eh", "cg"); err != nil {
+		t.Fatalf("failed to list ownership: %v", err)
+	}
+	if downloads != 1 {
+		t.Fatalf("expected the ownerships to reuse the downloaded leases, got %d downloads", downloads)
+	}
+
+	// a listing is only used once and only while it's recent
+	if _, err := store.ListOwnership(ctx, "ns", "eh", "cg"); err != nil {
+		t.Fatalf("failed to list ownership: %v", err)
+	}
+	if _, err := store.ListCheckpoints(ctx, "ns", "eh", "cg"); err != nil {
+		t.Fatalf("failed to list checkpoints: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := store.ListOwnership(ctx, "ns", "eh", "cg"); err != nil {
+		t.Fatalf("failed to list ownership: %v", err)
+	}
+	if downloads != 4 {
+		t.Fatalf("expected 4 downloads, got %d", downloads)
+	}
+}
+
+func TestGetBlobStoresMatchesOnlyLegacyEventHubCaseInsensitively(t *testing.T) {
+	pool := NewClientPool(nil)
+	storedGroups := StoredGroupsMap{
+		{Endpoint: "account.blob.core.windows.net", Container: "myeventhub", Legacy: true}: {
+			{Namespace: "my-ns.servicebus.windows.net", Eventhub: "myeventhub", ConsumerGroup: "cg"},
+		},
+	}
+
+	stores, err := pool.GetBlobStores(storedGroups, "my-ns.servicebus.windows.net", "MyEventHub")
+	if err != nil {
+		t.Fatalf("failed to get blob stores: %v", err)
+	}
+
+	if _, ok := stores["cg"].(*LegacyCheckpointStore); !ok {
+		t.Fatalf("expected the legacy checkpoint store of cg, got %v", stores)
+	}
+
+	// the checkpoints of other containers are listed by the exact names
+	storedGroups = StoredGroupsMap{
+		{Endpoint: "account.blob.core.windows.net", Container: "checkpoints"}: {
+			{Namespace: "my-ns.servicebus.windows.net", Eventhub: "myeventhub", ConsumerGroup: "cg"},
+		},
+	}
+
+	stores, err = pool.GetBlobStores(storedGroups, "my-ns.servicebus.windows.net", "MyEventHub")
+	if err != nil {
+		t.Fatalf("failed to get blob stores: %v", err)
+	}
+
+	if len(stores) != 0 {
+		t.Fatalf("expected no checkpoint store for an eventhub of different case, got %v", stores)
+	}
+}
@@ -0,0 +1,153 @@
+package blobstorage
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/deviceinsight/eventhub-metrics/internal/eventhub"
+)
+
+// ClientPool keeps the blob clients of storage accounts and the checkpoint stores of storage containers for the
+// lifetime of the process, so their connections are reused across eventhubs and collection cycles.
+type ClientPool struct {
+	credential *azidentity.DefaultAzureCredential
+
+	mu          sync.Mutex
+	blobClients map[string]*azblob.Client
+	blobStores  map[StorageContainer]eventhub.CheckpointStore
+	stats       ClientPoolStats
+}
+
+// ClientPoolStats reports how often the pool created a client or reused an existing one. Every request for a blob
+// client or checkpoint store counts once, the blob client a new checkpoint store is created with doesn't count.
+type ClientPoolStats struct {
+	Clients int
+	Created int64
+	Reused  int64
+}
+
+func NewClientPool(credential *azidentity.DefaultAzureCredential) *ClientPool {
+	return &ClientPool{
+		credential:  credential,
+		blobClients: make(map[string]*azblob.Client),
+		blobStores:  make(map[StorageContainer]eventhub.CheckpointStore),
+	}
+}
+
+// Stats returns the number of pooled clients and checkpoint stores and how often they were created or reused.
+func (p *ClientPool) Stats() ClientPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.Clients = len(p.blobClients) + len(p.blobStores)
+	return stats
+}
+
+// GetBlobStore returns the checkpoint store of a storage container.
+func (p *ClientPool) GetBlobStore(storageContainer StorageContainer) (eventhub.CheckpointStore, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if blobStore, ok := p.blobStores[storageContainer]; ok {
+		p.stats.Reused++
+		return blobStore, nil
+	}
+
+	blobClient, _, err := p.getBlobClientLocked(storageContainer.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	containerClient := blobClient.ServiceClient().NewContainerClient(storageContainer.Container)
+
+	var blobStore eventhub.CheckpointStore
+	if storageContainer.Legacy {
+		blobStore = newLegacyCheckpointStore(containerClient, storageContainer.Prefix)
+	} else {
+		blobStore = newCheckpointStore(containerClient, storageContainer.Prefix)
+	}
+
+	p.blobStores[storageContainer] = blobStore
+	p.stats.Created++
+
+	return blobStore, nil
+}
+
+// GetBlobStores returns the checkpoint stores of all consumer groups of an eventhub which are stored in containers.
+func (p *ClientPool) GetBlobStores(storedGroupsMap StoredGroupsMap,
+	namespace, eventHub string) (map[string]eventhub.CheckpointStore, error) {
+
+	consumerGroupBlobStores := make(map[string]eventhub.CheckpointStore)
+
+	for storageContainer, storedConsumerGroups := range storedGroupsMap {
+		for _, storedConsumerGroup := range storedConsumerGroups {
+			if !matchesEventHub(storageContainer, storedConsumerGroup, namespace, eventHub) {
+				continue
+			}
+
+			blobStore, err := p.GetBlobStore(storageContainer)
+			if err != nil {
+				return nil, fmt.Errorf("unable to create blob store for endpoint=%s, error=%w",
+					storageContainer.Endpoint, err)
+			}
+
+			consumerGroupBlobStores[storedConsumerGroup.ConsumerGroup] = blobStore
+		}
+	}
+
+	return consumerGroupBlobStores, nil
+}
+
+// matchesEventHub reports whether the consumer group stored in the container belongs to the eventhub. Legacy
+// containers are named after the eventhub in lowercase, while the blob names of other containers include the exact
+// names, which are case-sensitive when the checkpoints are listed.
+func matchesEventHub(storageContainer StorageContainer, storedConsumerGroup StoredConsumerGroup,
+	namespace, eventHub string) bool {
+
+	if storageContainer.Legacy {
+		return strings.EqualFold(storedConsumerGroup.Namespace, namespace) &&
+			strings.EqualFold(storedConsumerGroup.Eventhub, eventHub)
+	}
+	return storedConsumerGroup.Namespace == namespace && storedConsumerGroup.Eventhub == eventHub
+}
+
+func (p *ClientPool) getBlobClient(endpoint string) (*azblob.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	blobClient, created, err := p.getBlobClientLocked(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		p.stats.Created++
+	} else {
+		p.stats.Reused++
+	}
+	return blobClient, nil
+}
+
+// getBlobClientLocked returns the blob client of a storage account and whether it was created.
+func (p *ClientPool) getBlobClientLocked(endpoint string) (*azblob.Client, bool, error) {
+	if blobClient, ok := p.blobClients[endpoint]; ok {
+		return blobClient, false, nil
+	}
+
+	storageServiceURL, err := getStorageServiceURL(endpoint)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse storage service url: %w", err)
+	}
+
+	blobClient, err := azblob.NewClient(storageServiceURL.String(), p.credential, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating blob client: %w", err)
+	}
+
+	p.blobClients[endpoint] = blobClient
+	return blobClient, true, nil
+}
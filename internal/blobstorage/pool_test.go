@@ -0,0 +1,30 @@
+package blobstorage
+
+import "testing"
+
+func TestClientPoolStats(t *testing.T) {
+	pool := NewClientPool(nil)
+	first := StorageContainer{Endpoint: "account.blob.core.windows.net", Container: "first"}
+	second := StorageContainer{Endpoint: "account.blob.core.windows.net", Container: "second"}
+
+	for _, storageContainer := range []StorageContainer{first, first, second} {
+		if _, err := pool.GetBlobStore(storageContainer); err != nil {
+			t.Fatalf("failed to get blob store: %v", err)
+		}
+	}
+
+	// the blob client of the second container is reused internally, which isn't a request of the pool
+	expected := ClientPoolStats{Clients: 3, Created: 2, Reused: 1}
+	if stats := pool.Stats(); stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+
+	if _, err := pool.getBlobClient("account.blob.core.windows.net"); err != nil {
+		t.Fatalf("failed to get blob client: %v", err)
+	}
+
+	expected.Reused++
+	if stats := pool.Stats(); stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+}
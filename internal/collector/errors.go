@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+	"github.com/deviceinsight/eventhub-metrics/internal/rest"
+)
+
+// error classes reported by the collection errors metric.
+const (
+	ErrorClassAuthentication = "authentication"
+	ErrorClassThrottling     = "throttling"
+	ErrorClassTimeout        = "timeout"
+	ErrorClassNotFound       = "not_found"
+	ErrorClassServerError    = "server_error"
+	ErrorClassAMQP           = "amqp"
+	ErrorClassStorage        = "storage"
+	ErrorClassUnknown        = "unknown"
+)
+
+// ClassifyError returns the class of an error which occurred while collecting the metrics of a target.
+func ClassifyError(err error) string {
+	if errors.Is(err, rest.ErrAuthentication) {
+		return ErrorClassAuthentication
+	}
+
+	var statusErr *rest.StatusError
+	if errors.As(err, &statusErr) {
+		return classifyStatusCode(statusErr.StatusCode, ErrorClassUnknown)
+	}
+
+	// storage requests fail with a response error of the azure sdk
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return classifyStatusCode(respErr.StatusCode, ErrorClassStorage)
+	}
+
+	var amqpErr *azeventhubs.Error
+	if errors.As(err, &amqpErr) {
+		if amqpErr.Code == azeventhubs.ErrorCodeUnauthorizedAccess {
+			return ErrorClassAuthentication
+		}
+		return ErrorClassAMQP
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorClassTimeout
+	}
+
+	return ErrorClassUnknown
+}
+
+func classifyStatusCode(statusCode int, fallback string) string {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrorClassAuthentication
+	case statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable:
+		return ErrorClassThrottling
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return ErrorClassTimeout
+	case statusCode == http.StatusNotFound:
+		return ErrorClassNotFound
+	case statusCode >= http.StatusInternalServerError:
+		return ErrorClassServerError
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,35 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/deviceinsight/eventhub-metrics/internal/rest"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{err: fmt.Errorf("%w: %w", rest.ErrAuthentication, &rest.StatusError{StatusCode: http.StatusUnauthorized}),
+			expected: ErrorClassAuthentication},
+		{err: &rest.StatusError{StatusCode: http.StatusTooManyRequests}, expected: ErrorClassThrottling},
+		{err: &rest.StatusError{StatusCode: http.StatusNotFound}, expected: ErrorClassNotFound},
+		{err: &rest.StatusError{StatusCode: http.StatusBadGateway}, expected: ErrorClassServerError},
+		{err: &azcore.ResponseError{StatusCode: http.StatusForbidden}, expected: ErrorClassAuthentication},
+		{err: &azcore.ResponseError{StatusCode: http.StatusConflict}, expected: ErrorClassStorage},
+		{err: fmt.Errorf("failed to list checkpoints: %w", context.DeadlineExceeded), expected: ErrorClassTimeout},
+		{err: errors.New("failure"), expected: ErrorClassUnknown},
+	}
+
+	for _, test := range tests {
+		if errorClass := ClassifyError(test.err); errorClass != test.expected {
+			t.Errorf("expected %s for %v, got %s", test.expected, test.err, errorClass)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/deviceinsight/eventhub-metrics/internal/eventhub"
+	"github.com/deviceinsight/eventhub-metrics/internal/metrics"
+	"github.com/deviceinsight/eventhub-metrics/internal/rest"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/oauth"
+)
+
+// kafkaPort is the port of the Kafka endpoint of an Event Hubs namespace.
+const kafkaPort = "9093"
+
+// KafkaService collects the lag of Kafka consumer groups, which are managed by the Kafka endpoint of a namespace
+// instead of a checkpoint store.
+type KafkaService interface {
+	ProcessNamespace(ctx context.Context, credential *azidentity.DefaultAzureCredential, endpoint string,
+		includedEventHubsRegex, excludedEventHubsRegex, excludeConsumerGroupsRegex *regexp.Regexp) error
+}
+
+type kafkaClientFactory func(credential *azidentity.DefaultAzureCredential, endpoint string) (*kgo.Client, error)
+
+type kafkaService struct {
+	metrics   metrics.Service
+	newClient kafkaClientFactory
+}
+
+func NewKafkaService(metrics metrics.Service) KafkaService {
+	return &kafkaService{
+		metrics:   metrics,
+		newClient: newKafkaClient,
+	}
+}
+
+func (s *kafkaService) ProcessNamespace(ctx context.Context, credential *azidentity.DefaultAzureCredential,
+	endpoint string, includedEventHubsRegex, excludedEventHubsRegex, excludeConsumerGroupsRegex *regexp.Regexp) error {
+
+	namespace, err := eventhub.GetNamespaceName(endpoint)
+	if err != nil {
+		err = fmt.Errorf("failed get namespace name: %w", err)
+		recordError(s.metrics, endpoint, "", "", err)
+		return err
+	}
+
+	client, err := s.newClient(credential, endpoint)
+	if err != nil {
+		err = fmt.Errorf("failed to create kafka client: %w", err)
+		recordError(s.metrics, namespace, "", "", err)
+		return err
+	}
+	defer client.Close()
+
+	admClient := kadm.NewClient(client)
+
+	groups, err := admClient.ListGroups(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to list kafka consumer groups: %w", err)
+		recordError(s.metrics, namespace, "", "", err)
+		return err
+	}
+
+	committedOffsets := make(map[string]kadm.OffsetResponses)
+	var topics []string
+	var errs []error
+	seenTopics := make(map[string]bool)
+
+	for _, group := range groups.Sorted() {
+		if excludeConsumerGroupsRegex != nil && excludeConsumerGroupsRegex.MatchString(group.Group) {
+			slog.Debug("skipping excluded kafka consumerGroup", "consumerGroup", group.Group)
+			continue
+		}
+
+		offsets, err := admClient.FetchOffsets(ctx, group.Group)
+		if err != nil {
+			err = fmt.Errorf("failed to fetch offsets of kafka consumer group %s: %w", group.Group, err)
+			recordError(s.metrics, namespace, "", group.Group, err)
+			errs = append(errs, err)
+			continue
+		}
+
+		offsets.KeepFunc(func(offset kadm.OffsetResponse) bool {
+			return isEventHubIncluded(offset.Topic, includedEventHubsRegex, excludedEventHubsRegex)
+		})
+
+		for topic := range offsets {
+			if !seenTopics[topic] {
+				seenTopics[topic] = true
+				topics = append(topics, topic)
+			}
+		}
+		committedOffsets[group.Group] = offsets
+	}
+
+	if len(topics) == 0 {
+		return errors.Join(errs...)
+	}
+
+	endOffsets, err := admClient.ListEndOffsets(ctx, topics...)
+	if err != nil {
+		err = fmt.Errorf("failed to list end offsets: %w", err)
+		recordError(s.metrics, namespace, "", "", err)
+		return errors.Join(append(errs, err)...)
+	}
+
+	states := getKafkaGroupStates(ctx, admClient, groups, committedOffsets)
+	for group, offsets := range committedOffsets {
+		s.recordLag(namespace, group, states[group], offsets, endOffsets)
+	}
+
+	return errors.Join(errs...)
+}
+
+// getKafkaGroupStates describes the consumer groups to get their state. The state of the listed groups is used if
+// describing them fails.
+func getKafkaGroupStates(ctx context.Context, admClient *kadm.Client, groups kadm.ListedGroups,
+	committedOffsets map[string]kadm.OffsetResponses) map[string]string {
+
+	states := make(map[string]string, len(committedOffsets))
+	names := make([]string, 0, len(committedOffsets))
+	for group := range committedOffsets {
+		states[group] = getKafkaGroupState(groups[group].State)
+		names = append(names, group)
+	}
+
+	described, err := admClient.DescribeGroups(ctx, names...)
+	if err != nil {
+		slog.Warn("failed to describe kafka consumer groups", "error", err)
+	}
+	for group, description := range described {
+		if description.Err == nil {
+			states[group] = getKafkaGroupState(description.State)
+		}
+	}
+
+	return states
+}
+
+// getKafkaGroupState maps the state of a Kafka consumer group to the states of Event Hubs consumer groups.
+func getKafkaGroupState(state string) string {
+	switch state {
+	case "Stable":
+		return "stable"
+	case "Empty":
+		return "empty"
+	case "PreparingRebalance", "CompletingRebalance":
+		return "rebalancing"
+	case "":
+		return "unknown"
+	default:
+		return strings.ToLower(state)
+	}
+}
+
+func (s *kafkaService) recordLag(namespace, consumerGroup, state string, committedOffsets kadm.OffsetResponses,
+	endOffsets kadm.ListedOffsets) {
+
+	for topic, partitions := range committedOffsets {
+		s.metrics.RecordConsumerGroupInfo(namespace, topic, consumerGroup, metrics.ProtocolKafka, state)
+		lagSum := int64(0)
+
+		for partition, committed := range partitions {
+			end, ok := endOffsets.Lookup(topic, partition)
+			if !ok || end.Err != nil || committed.Err != nil || committed.At < 0 {
+				slog.Debug("skipping kafka partition without offsets", "namespace", namespace, "eventHub", topic,
+					"consumerGroup", consumerGroup, "partition", partition)
+				continue
+			}
+
+			// the committed offset is the next offset the consumer group will read
+			lag := max(end.Offset-committed.At, 0)
+			lagSum += lag
+			s.metrics.RecordConsumerGroupPartitionLag(namespace, topic, consumerGroup,
+				strconv.FormatInt(int64(partition), 10), metrics.ProtocolKafka, lag)
+		}
+
+		s.metrics.RecordConsumerGroupLag(namespace, topic, consumerGroup, metrics.ProtocolKafka, lagSum)
+	}
+}
+
+func isEventHubIncluded(name string, includedEventHubsRegex, excludedEventHubsRegex *regexp.Regexp) bool {
+	if includedEventHubsRegex != nil && !includedEventHubsRegex.MatchString(name) {
+		return false
+	}
+	return excludedEventHubsRegex == nil || !excludedEventHubsRegex.MatchString(name)
+}
+
+// newKafkaClient connects to the Kafka endpoint of a namespace using an OAuth token of the given credential.
+func newKafkaClient(credential *azidentity.DefaultAzureCredential, endpoint string) (*kgo.Client, error) {
+	u, err := rest.GetURL(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kafka url: %w", err)
+	}
+
+	return kgo.NewClient(
+		kgo.SeedBrokers(net.JoinHostPort(u.Hostname(), kafkaPort)),
+		kgo.DialTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}),
+		kgo.SASL(oauth.Oauth(func(ctx context.Context) (oauth.Auth, error) {
+			token, err := rest.GetToken(ctx, credential, endpoint, "/.default")
+			if err != nil {
+				return oauth.Auth{}, err
+			}
+			return oauth.Auth{Token: token}, nil
+		})),
+	)
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/deviceinsight/eventhub-metrics/internal/metrics"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestKafkaProcessNamespaceRecordsLag(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(2, "eventhub-1", "excluded-hub"))
+	if err != nil {
+		t.Fatalf("failed to start kafka cluster: %v", err)
+	}
+	defer cluster.Close()
+
+	client, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...),
+		kgo.RecordPartitioner(kgo.ManualPartitioner()))
+	if err != nil {
+		t.Fatalf("failed to create kafka client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	// 5 records on partition 0 and 3 records on partition 1 of each topic
+	for _, topic := range []string{"eventhub-1", "excluded-hub"} {
+		for partition, count := range map[int32]int{0: 5, 1: 3} {
+			for range count {
+				record := &kgo.Record{Topic: topic, Partition: partition, Value: []byte("event")}
+				if err := client.ProduceSync(ctx, record).FirstErr(); err != nil {
+					t.Fatalf("failed to produce record: %v", err)
+				}
+			}
+		}
+	}
+
+	offsets := kadm.Offsets{}
+	offsets.Add(kadm.Offset{Topic: "eventhub-1", Partition: 0, At: 2})
+	offsets.Add(kadm.Offset{Topic: "eventhub-1", Partition: 1, At: 3})
+	offsets.Add(kadm.Offset{Topic: "excluded-hub", Partition: 0, At: 0})
+	if _, err := kadm.NewClient(client).CommitOffsets(ctx, "my-group", offsets); err != nil {
+		t.Fatalf("failed to commit offsets: %v", err)
+	}
+
+	recorder := newRecordingService()
+	s := &kafkaService{
+		metrics: metrics.NewDelegateService(recorder),
+		newClient: func(*azidentity.DefaultAzureCredential, string) (*kgo.Client, error) {
+			return kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...))
+		},
+	}
+
+	err = s.ProcessNamespace(ctx, nil, "my-ns.servicebus.windows.net", nil, regexp.MustCompile("excluded"), nil)
+	if err != nil {
+		t.Fatalf("failed to process namespace: %v", err)
+	}
+
+	expected := map[string]float64{
+		"consumer_group_partition_lag{consumer_group=my-group,eh_namespace=my-ns,eventhub=eventhub-1," +
+			"partition_id=0,protocol=kafka}": 3,
+		"consumer_group_partition_lag{consumer_group=my-group,eh_namespace=my-ns,eventhub=eventhub-1," +
+			"partition_id=1,protocol=kafka}": 0,
+		"consumer_group_lag{consumer_group=my-group,eh_namespace=my-ns,eventhub=eventhub-1,protocol=kafka}": 3,
+		// the consumer group committed offsets without having members
+		"consumer_group_info{consumer_group=my-group,eh_namespace=my-ns,eventhub=eventhub-1,protocol=kafka," +
+			"state=empty}": 0,
+	}
+
+	if len(recorder.values) != len(expected) {
+		t.Fatalf("expected %d recorded metrics, got %v", len(expected), recorder.values)
+	}
+	for key, value := range expected {
+		if got, ok := recorder.values[key]; !ok || got != value {
+			t.Errorf("expected %s to be %v, got %v (recorded: %t)", key, value, got, ok)
+		}
+	}
+}
+
+// recordingService keeps the last recorded value of every series.
+type recordingService struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newRecordingService() *recordingService {
+	return &recordingService{values: make(map[string]float64)}
+}
+
+func (s *recordingService) RecordMetric(metric *metrics.Metric, labels map[string]string, value float64) {
+	pairs := make([]string, 0, len(labels))
+	for key, labelValue := range labels {
+		pairs = append(pairs, key+"="+labelValue)
+	}
+	sort.Strings(pairs)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[metric.Name+"{"+strings.Join(pairs, ",")+"}"] = value
+}
+
+func (s *recordingService) StartCycle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]float64)
+}
+
+func (s *recordingService) PushMetrics() error {
+	return nil
+}
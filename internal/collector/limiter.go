@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+	"github.com/deviceinsight/eventhub-metrics/internal/eventhub"
+	"golang.org/x/sync/semaphore"
+)
+
+// limitedCheckpointStore bounds the number of requests to checkpoint stores which are in flight across all eventhubs
+// and consumer groups, so the storage accounts don't start throttling.
+type limitedCheckpointStore struct {
+	store    eventhub.CheckpointStore
+	requests *semaphore.Weighted
+}
+
+func newLimitedCheckpointStore(store eventhub.CheckpointStore, requests *semaphore.Weighted) eventhub.CheckpointStore {
+	if requests == nil {
+		return store
+	}
+	return &limitedCheckpointStore{store: store, requests: requests}
+}
+
+func (s *limitedCheckpointStore) ListCheckpoints(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]eventhub.Checkpoint, error) {
+
+	if err := s.requests.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	defer s.requests.Release(1)
+
+	return s.store.ListCheckpoints(ctx, namespace, eventHub, consumerGroup)
+}
+
+func (s *limitedCheckpointStore) ListOwnership(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]azeventhubs.Ownership, error) {
+
+	if err := s.requests.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	defer s.requests.Release(1)
+
+	return s.store.ListOwnership(ctx, namespace, eventHub, consumerGroup)
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Task is a unit of work of a collection cycle, e.g. processing an eventhub.
+type Task func(ctx context.Context) error
+
+// Scheduler runs the tasks of all namespaces with a shared number of workers. Workers take the tasks of the
+// namespaces in turn, so a namespace with many eventhubs or a slow region can't delay the other namespaces.
+// Tasks may submit further tasks while they are running. The workers only limit the tasks, not the work a task
+// fans out itself, e.g. the consumer groups of an eventhub.
+type Scheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  map[string][]Task
+	order   []string
+	next    int
+	running int
+}
+
+func NewScheduler() *Scheduler {
+	s := &Scheduler{queues: make(map[string][]Task)}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Submit queues a task of a namespace.
+func (s *Scheduler) Submit(namespace string, task Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.queues[namespace]; !ok {
+		s.order = append(s.order, namespace)
+	}
+	s.queues[namespace] = append(s.queues[namespace], task)
+	s.cond.Signal()
+}
+
+// Run executes the queued tasks with the given number of workers until no task is queued or running anymore.
+// A failing task doesn't affect the other tasks, so the results of all healthy targets are kept. The errors of all
+// failed tasks are returned joined.
+func (s *Scheduler) Run(ctx context.Context, workers int) error {
+	var wg sync.WaitGroup
+	var errsMu sync.Mutex
+	var errs []error
+
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				task, ok := s.take()
+				if !ok {
+					return
+				}
+
+				err := task(ctx)
+				s.done()
+				if err != nil {
+					errsMu.Lock()
+					errs = append(errs, err)
+					errsMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// take waits for the next task. It returns false once all tasks are done, since only running tasks can submit
+// further tasks.
+func (s *Scheduler) take() (Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if task, ok := s.pop(); ok {
+			s.running++
+			return task, true
+		}
+
+		if s.running == 0 {
+			s.cond.Broadcast()
+			return nil, false
+		}
+
+		s.cond.Wait()
+	}
+}
+
+func (s *Scheduler) done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running--
+	s.cond.Broadcast()
+}
+
+// pop removes the next task in round-robin order of the namespaces.
+func (s *Scheduler) pop() (Task, bool) {
+	for range len(s.order) {
+		namespace := s.order[s.next]
+		s.next = (s.next + 1) % len(s.order)
+
+		if tasks := s.queues[namespace]; len(tasks) > 0 {
+			s.queues[namespace] = tasks[1:]
+			return tasks[0], true
+		}
+	}
+
+	return nil, false
+}
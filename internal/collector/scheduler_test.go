@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSchedulerInterleavesNamespaces(t *testing.T) {
+	scheduler := NewScheduler()
+
+	var mu sync.Mutex
+	var executed []string
+
+	record := func(name string) Task {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			executed = append(executed, name)
+			return nil
+		}
+	}
+
+	scheduler.Submit("ns-a", func(ctx context.Context) error {
+		for _, name := range []string{"a1", "a2", "a3"} {
+			scheduler.Submit("ns-a", record(name))
+		}
+		return nil
+	})
+	scheduler.Submit("ns-b", func(ctx context.Context) error {
+		scheduler.Submit("ns-b", record("b1"))
+		return nil
+	})
+
+	if err := scheduler.Run(context.Background(), 1); err != nil {
+		t.Fatalf("failed to run tasks: %v", err)
+	}
+
+	if got := strings.Join(executed, ","); got != "a1,b1,a2,a3" {
+		t.Fatalf("expected tasks to be interleaved, got %s", got)
+	}
+}
+
+func TestSchedulerIsolatesErrors(t *testing.T) {
+	scheduler := NewScheduler()
+	failure := errors.New("failure")
+
+	var completed atomic.Bool
+
+	scheduler.Submit("ns-a", func(context.Context) error { return failure })
+	scheduler.Submit("ns-b", func(ctx context.Context) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		completed.Store(true)
+		return nil
+	})
+
+	if err := scheduler.Run(context.Background(), 1); !errors.Is(err, failure) {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+
+	if !completed.Load() {
+		t.Fatal("expected the task of the other namespace to complete")
+	}
+}
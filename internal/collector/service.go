@@ -2,77 +2,228 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"regexp"
+	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
-	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2/checkpoints"
 	"github.com/deviceinsight/eventhub-metrics/internal/config"
 	"github.com/deviceinsight/eventhub-metrics/internal/eventhub"
 	"github.com/deviceinsight/eventhub-metrics/internal/metrics"
+	"github.com/deviceinsight/eventhub-metrics/internal/rest"
+	"github.com/deviceinsight/eventhub-metrics/internal/tracing"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 )
 
+// noCatchUp is reported as catch-up time of a consumer group whose lag isn't shrinking.
+const noCatchUp = -1
+
 type Service interface {
 	ProcessNamespace(ctx context.Context, credential *azidentity.DefaultAzureCredential,
 		endpoint string) (string, []eventhub.Details, error)
 	ProcessEventHub(ctx context.Context, credential *azidentity.DefaultAzureCredential,
-		blobStores map[string]*checkpoints.BlobStore, namespace, endpoint string, eventHubDetails *eventhub.Details,
-		excludeConsumerGroupsRegex *regexp.Regexp) error
+		checkpointStores map[string]eventhub.CheckpointStore, namespace, endpoint string,
+		eventHubDetails *eventhub.Details, excludeConsumerGroupsRegex *regexp.Regexp) error
 }
 
 type service struct {
-	metrics metrics.Service
-	cfg     config.CollectorConfig
+	metrics         metrics.Service
+	cfg             config.CollectorConfig
+	consumerClients *eventhub.ConsumerClientPool
+	storageRequests *semaphore.Weighted
+	enqueuedTimes   *enqueuedTimeCache
+	ingress         *rateTracker
+	consumption     *rateTracker
+	checkpoints     *checkpointTracker
+	owners          *ownerTracker
+	now             func() time.Time
+	enqueuedTime    func(ctx context.Context, consumerClient *azeventhubs.ConsumerClient, partitionID string,
+		sequenceNumber int64) (time.Time, error)
 }
 
-func NewService(metrics metrics.Service, cfg config.CollectorConfig) Service {
+func NewService(metrics metrics.Service, cfg config.CollectorConfig,
+	consumerClients *eventhub.ConsumerClientPool) Service {
+
+	var storageRequests *semaphore.Weighted
+	if cfg.MaxStorageRequests > 0 {
+		storageRequests = semaphore.NewWeighted(int64(cfg.MaxStorageRequests))
+	}
+
 	return &service{
-		metrics: metrics,
-		cfg:     cfg,
+		metrics:         metrics,
+		cfg:             cfg,
+		consumerClients: consumerClients,
+		storageRequests: storageRequests,
+		enqueuedTimes:   newEnqueuedTimeCache(),
+		ingress:         newRateTracker(),
+		consumption:     newRateTracker(),
+		checkpoints:     newCheckpointTracker(),
+		owners:          newOwnerTracker(),
+		now:             time.Now,
+		enqueuedTime:    eventhub.GetEnqueuedTime,
 	}
 }
 
 func (s *service) ProcessNamespace(ctx context.Context, credential *azidentity.DefaultAzureCredential,
 	endpoint string) (string, []eventhub.Details, error) {
 
+	ctx, span := tracing.Start(ctx, "collector.ProcessNamespace", tracing.AttributeEndpoint.String(endpoint))
+	namespace, eventHubs, err := s.processNamespace(ctx, credential, endpoint)
+	if namespace != "" {
+		span.SetAttributes(tracing.AttributeNamespace.String(namespace))
+	}
+	tracing.End(span, err)
+	return namespace, eventHubs, err
+}
+
+func (s *service) processNamespace(ctx context.Context, credential *azidentity.DefaultAzureCredential,
+	endpoint string) (string, []eventhub.Details, error) {
+
 	namespace, err := eventhub.GetNamespaceName(endpoint)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed get namespace name: %w", err)
+		err = fmt.Errorf("failed get namespace name: %w", err)
+		recordError(s.metrics, endpoint, "", "", err)
+		return "", nil, err
 	}
 
 	s.metrics.RecordNamespaceInfo(namespace, endpoint)
 	eventHubs, err := eventhub.GetEventHubs(ctx, credential, endpoint)
-	return namespace, eventHubs, err
+	if err != nil {
+		s.metrics.RecordStageError(managementStage(err))
+		recordError(s.metrics, namespace, "", "", err)
+		return namespace, nil, err
+	}
+
+	s.consumerClients.Retain(endpoint, eventHubs)
+
+	existing := make(map[string]bool, len(eventHubs))
+	for _, eventHub := range eventHubs {
+		existing[eventHub.Name] = true
+	}
+	s.retainState(func(key partitionKey) bool { return key.namespace != namespace || existing[key.eventHub] })
+
+	return namespace, eventHubs, nil
+}
+
+// retainConsumerGroups forgets the state of all consumer groups of an eventhub which don't exist anymore. The
+// ingress of the eventhub is tracked without consumer group.
+func (s *service) retainConsumerGroups(namespace, eventHub string, consumerGroups []string) {
+	existing := make(map[string]bool, len(consumerGroups))
+	for _, consumerGroup := range consumerGroups {
+		existing[consumerGroup] = true
+	}
+	s.retainState(func(key partitionKey) bool {
+		return key.namespace != namespace || key.eventHub != eventHub || key.consumerGroup == "" ||
+			existing[key.consumerGroup]
+	})
+}
+
+// retainState forgets the state kept across collection cycles of all partitions which aren't kept, so deleted
+// eventhubs and consumer groups don't leak memory.
+func (s *service) retainState(keep func(partitionKey) bool) {
+	s.enqueuedTimes.retain(keep)
+	s.ingress.retain(keep)
+	s.consumption.retain(keep)
+	s.checkpoints.retain(keep)
+	s.owners.retain(keep)
+}
+
+// eventHub holds everything collected for an event hub that is shared by all of its consumer groups.
+type eventHub struct {
+	namespace       string
+	endpoint        string
+	details         *eventhub.Details
+	consumerClient  *azeventhubs.ConsumerClient
+	sequenceNumbers map[string]eventhub.SequenceNumbers
+	ingressRates    map[string]float64
 }
 
 func (s *service) ProcessEventHub(ctx context.Context, credential *azidentity.DefaultAzureCredential,
-	blobStores map[string]*checkpoints.BlobStore, namespace, endpoint string, eventHubDetails *eventhub.Details,
-	excludeConsumerGroupsRegex *regexp.Regexp) error {
+	checkpointStores map[string]eventhub.CheckpointStore, namespace, endpoint string,
+	eventHubDetails *eventhub.Details, excludeConsumerGroupsRegex *regexp.Regexp) error {
+
+	ctx, span := tracing.Start(ctx, "collector.ProcessEventHub", tracing.AttributeNamespace.String(namespace),
+		tracing.AttributeEndpoint.String(endpoint), tracing.AttributeEventHub.String(eventHubDetails.Name))
+	err := s.processEventHub(ctx, credential, checkpointStores, namespace, endpoint, eventHubDetails,
+		excludeConsumerGroupsRegex)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *service) processEventHub(ctx context.Context, credential *azidentity.DefaultAzureCredential,
+	checkpointStores map[string]eventhub.CheckpointStore, namespace, endpoint string,
+	eventHubDetails *eventhub.Details, excludeConsumerGroupsRegex *regexp.Regexp) error {
 
 	consumerGroups, err := eventhub.GetConsumerGroups(ctx, credential, endpoint, eventHubDetails.Name)
 	if err != nil {
-		return fmt.Errorf("failed to get consumer groups: %w", err)
+		s.metrics.RecordStageError(managementStage(err))
+		err = fmt.Errorf("failed to get consumer groups: %w", err)
+		recordError(s.metrics, namespace, eventHubDetails.Name, "", err)
+		return err
 	}
-	sequenceNumbers, err := eventhub.GetSequenceNumbers(ctx, credential, endpoint, eventHubDetails)
+
+	s.retainConsumerGroups(namespace, eventHubDetails.Name, consumerGroups)
+
+	consumerClient, sequenceNumbers, err := s.getSequenceNumbers(ctx, endpoint, eventHubDetails)
 	if err != nil {
-		return fmt.Errorf("failed to get sequence numbers: %w", err)
+		err = fmt.Errorf("failed to get sequence numbers: %w", err)
+		recordError(s.metrics, namespace, eventHubDetails.Name, "", err)
+		return err
 	}
+	observedAt := s.now()
 
 	s.metrics.RecordEventhubInfo(namespace, eventHubDetails.Name, eventHubDetails.PartitionCount,
 		eventHubDetails.MessageRetentionInDays)
 
+	hub := &eventHub{
+		namespace:       namespace,
+		endpoint:        endpoint,
+		details:         eventHubDetails,
+		consumerClient:  consumerClient,
+		sequenceNumbers: sequenceNumbers,
+		ingressRates:    make(map[string]float64),
+	}
+
 	seqSum := eventhub.SequenceNumbers{}
+	ingressRateSum := float64(0)
 
 	for partitionID, seq := range sequenceNumbers {
 		s.metrics.RecordEventhubPartitionSequenceNumber(namespace, eventHubDetails.Name, partitionID,
 			seq.Min, seq.Max)
 		seqSum.Min += seq.Min
 		seqSum.Max += seq.Max
+
+		key := partitionKey{namespace: namespace, eventHub: eventHubDetails.Name, partitionID: partitionID}
+		if rate, ok := s.ingress.rate(key, seq.Max, observedAt); ok {
+			hub.ingressRates[partitionID] = rate
+			ingressRateSum += rate
+			s.metrics.RecordEventhubPartitionIngressRate(namespace, eventHubDetails.Name, partitionID, rate)
+		}
 	}
 
 	s.metrics.RecordEventhubSequenceNumberSum(namespace, eventHubDetails.Name, seqSum.Min, seqSum.Max)
+	if len(hub.ingressRates) > 0 {
+		s.metrics.RecordEventhubIngressRate(namespace, eventHubDetails.Name, ingressRateSum)
+	}
+
+	return s.processConsumerGroups(ctx, checkpointStores, hub, consumerGroups, excludeConsumerGroupsRegex)
+}
+
+// processConsumerGroups processes the consumer groups of an eventhub concurrently. A failing consumer group doesn't
+// affect the others, the errors of all failed consumer groups are returned joined.
+func (s *service) processConsumerGroups(ctx context.Context, checkpointStores map[string]eventhub.CheckpointStore,
+	hub *eventHub, consumerGroups []string, excludeConsumerGroupsRegex *regexp.Regexp) error {
+
+	var g errgroup.Group
+	g.SetLimit(max(s.cfg.ConsumerGroupConcurrency, 1))
+
+	var errsMu sync.Mutex
+	var errs []error
 
 	for _, consumerGroup := range consumerGroups {
 
@@ -81,76 +232,344 @@ func (s *service) ProcessEventHub(ctx context.Context, credential *azidentity.De
 			continue
 		}
 
-		blobStore, ok := blobStores[consumerGroup]
+		checkpointStore, ok := checkpointStores[consumerGroup]
 		if !ok {
 			slog.Warn("consumerGroup without associated checkpointStore", "consumerGroup", consumerGroup)
 			continue
 		}
 
-		if err := s.processConsumerGroup(ctx, blobStore, endpoint, eventHubDetails.Name, consumerGroup, sequenceNumbers,
-			namespace, eventHubDetails.PartitionCount); err != nil {
-			return err
+		checkpointStore = newLimitedCheckpointStore(checkpointStore, s.storageRequests)
+
+		g.Go(func() error {
+			groupCtx, span := tracing.Start(ctx, "collector.processConsumerGroup",
+				tracing.AttributeConsumerGroup.String(consumerGroup))
+			err := s.processConsumerGroup(groupCtx, checkpointStore, hub, consumerGroup)
+			tracing.End(span, err)
+
+			if err != nil {
+				// the checkpoints of any store couldn't be read. a cycle which exceeded its timeout is reported by
+				// the partial cycle instead.
+				if ctx.Err() == nil {
+					s.metrics.RecordStageError(metrics.StageCheckpoint)
+				}
+				recordError(s.metrics, hub.namespace, hub.details.Name, consumerGroup, err)
+
+				errsMu.Lock()
+				errs = append(errs, fmt.Errorf("failed to process consumerGroup %s: %w", consumerGroup, err))
+				errsMu.Unlock()
+			}
+			return nil
+		})
+	}
+
+	_ = g.Wait()
+	return errors.Join(errs...)
+}
+
+// managementStage returns the pipeline stage of a failed request to the management endpoint of a namespace.
+func managementStage(err error) string {
+	if errors.Is(err, rest.ErrToken) {
+		return metrics.StageToken
+	}
+	return metrics.StageManagement
+}
+
+// recordError reports a namespace, eventhub or consumer group whose metrics couldn't be collected.
+func recordError(metricsService metrics.Service, namespace, eventHub, consumerGroup string, err error) {
+	errorClass := ClassifyError(err)
+	slog.Warn("failed to collect metrics", "namespace", namespace, "eventHub", eventHub,
+		"consumerGroup", consumerGroup, "errorClass", errorClass, "error", err)
+	metricsService.RecordCollectionError(namespace, eventHub, consumerGroup, errorClass)
+}
+
+// getSequenceNumbers queries the partitions with the pooled consumer client of the eventhub. A failed client is
+// replaced by a new connection once.
+func (s *service) getSequenceNumbers(ctx context.Context, endpoint string,
+	eventHubDetails *eventhub.Details) (*azeventhubs.ConsumerClient, map[string]eventhub.SequenceNumbers, error) {
+
+	var lastErr error
+
+	for range 2 {
+		consumerClient, err := s.consumerClients.Get(endpoint, eventHubDetails.Name)
+		if err != nil {
+			s.metrics.RecordStageError(metrics.StageAMQP)
+			return nil, nil, err
+		}
+
+		sequenceNumbers, err := eventhub.GetSequenceNumbers(ctx, consumerClient, eventHubDetails,
+			s.cfg.PartitionConcurrency)
+		if err == nil {
+			return consumerClient, sequenceNumbers, nil
+		}
+
+		lastErr = err
+		s.metrics.RecordStageError(metrics.StageAMQP)
+		s.consumerClients.Invalidate(endpoint, eventHubDetails.Name, consumerClient)
+
+		if ctx.Err() != nil {
+			break
+		}
+		slog.Warn("consumer client failed, reconnecting", "eventhub", eventHubDetails.Name, "error", err)
+	}
+
+	return nil, nil, lastErr
+}
+
+func (s *service) processConsumerGroup(ctx context.Context, checkpointStore eventhub.CheckpointStore,
+	hub *eventHub, consumerGroup string) error {
+
+	stuck, err := s.processCheckpoints(ctx, checkpointStore, hub, consumerGroup)
+	if err != nil {
+		return err
+	}
+
+	namespace, eventHub := hub.namespace, hub.details.Name
+
+	ownerships, err := checkpointStore.ListOwnership(ctx, hub.endpoint, eventHub, consumerGroup)
+	if err != nil {
+		return fmt.Errorf("failed to list ownership: %w", err)
+	}
+	observedAt := s.now()
+
+	var activeOwnerships []azeventhubs.Ownership
+	rebalancing := false
+
+	for _, ownership := range ownerships {
+		expired := eventhub.IsOwnershipExpired(ownership, s.cfg.OwnershipExpirationDuration)
+		if !expired {
+			activeOwnerships = append(activeOwnerships, ownership)
+		}
+		s.metrics.RecordConsumerGroupPartitionOwner(namespace, eventHub, consumerGroup, ownership.PartitionID,
+			ownership.OwnerID, expired)
+
+		key := partitionKey{namespace: namespace, eventHub: eventHub, consumerGroup: consumerGroup,
+			partitionID: ownership.PartitionID}
+		history, changed := s.owners.observe(key, ownership.OwnerID, observedAt)
+		if changed {
+			rebalancing = true
 		}
+		s.metrics.RecordConsumerGroupPartitionOwnershipChanges(namespace, eventHub, consumerGroup,
+			ownership.PartitionID, history.changes, history.lastChangeAt)
 	}
+
+	s.metrics.RecordConsumerGroupOwners(namespace, eventHub, consumerGroup, len(activeOwnerships))
+
+	distribution := getOwnerDistribution(activeOwnerships)
+	s.metrics.RecordConsumerGroupOwnerDistribution(namespace, eventHub, consumerGroup, distribution.owners,
+		distribution.maxPartitions, distribution.minPartitions, distribution.imbalanceRatio)
+
+	var state string
+	switch {
+	case len(activeOwnerships) == 0:
+		state = "empty"
+	case stuck:
+		state = "stuck"
+	case rebalancing:
+		state = "rebalancing"
+	case len(activeOwnerships) == hub.details.PartitionCount:
+		state = "stable"
+	default:
+		state = "unstable"
+	}
+
+	s.metrics.RecordConsumerGroupInfo(namespace, eventHub, consumerGroup, metrics.ProtocolEventHubs, state)
 	return nil
 }
 
-func (s *service) processConsumerGroup(ctx context.Context, blobStore *checkpoints.BlobStore, endpoint string,
-	eventHub string, consumerGroup string, sequenceNumbers map[string]eventhub.SequenceNumbers, namespace string,
-	partitionCount int) error {
+// ownerDistribution describes how the owned partitions of a consumer group are spread across its owner instances.
+type ownerDistribution struct {
+	owners        int
+	maxPartitions int
+	minPartitions int
+	// imbalanceRatio is the partition count of the busiest owner divided by the average partition count per owner.
+	// A perfectly balanced consumer group reports 1.
+	imbalanceRatio float64
+}
 
-	checkpointList, err := blobStore.ListCheckpoints(ctx, endpoint, eventHub, consumerGroup, nil)
+func getOwnerDistribution(activeOwnerships []azeventhubs.Ownership) ownerDistribution {
+	partitionsPerOwner := make(map[string]int)
+	for _, ownership := range activeOwnerships {
+		partitionsPerOwner[ownership.OwnerID]++
+	}
+
+	if len(partitionsPerOwner) == 0 {
+		return ownerDistribution{}
+	}
+
+	distribution := ownerDistribution{owners: len(partitionsPerOwner), minPartitions: len(activeOwnerships)}
+	for _, partitions := range partitionsPerOwner {
+		distribution.maxPartitions = max(distribution.maxPartitions, partitions)
+		distribution.minPartitions = min(distribution.minPartitions, partitions)
+	}
+
+	average := float64(len(activeOwnerships)) / float64(distribution.owners)
+	distribution.imbalanceRatio = float64(distribution.maxPartitions) / average
+
+	return distribution
+}
+
+// processCheckpoints records the lag related metrics of a consumer group. It returns true if the consumer group is
+// lagging on a partition whose checkpoint didn't advance for at least the configured stuck duration.
+func (s *service) processCheckpoints(ctx context.Context, checkpointStore eventhub.CheckpointStore,
+	hub *eventHub, consumerGroup string) (bool, error) {
+
+	namespace, eventHub := hub.namespace, hub.details.Name
+
+	checkpointList, err := checkpointStore.ListCheckpoints(ctx, hub.endpoint, eventHub, consumerGroup)
 	if err != nil {
-		return fmt.Errorf("failed to list checkpoints: %w", err)
+		return false, fmt.Errorf("failed to list checkpoints: %w", err)
 	}
+	observedAt := s.now()
+	stuck := false
 
 	lagSum := int64(0)
 	sequenceSum := int64(0)
+	maxLagSeconds := float64(0)
+	hasLagSeconds := false
+	consumeRateSum := float64(0)
+	lagGrowthRateSum := float64(0)
+	hasConsumeRate := false
+	hasLagGrowthRate := false
 
 	for _, checkpoint := range checkpointList {
-		lag := sequenceNumbers[checkpoint.PartitionID].Max
+		seq := hub.sequenceNumbers[checkpoint.PartitionID]
+		lag := seq.Max
 		if checkpoint.SequenceNumber != nil {
-			lag = sequenceNumbers[checkpoint.PartitionID].Max - *checkpoint.SequenceNumber
+			lag = seq.Max - *checkpoint.SequenceNumber
 			sequenceSum += *checkpoint.SequenceNumber
 		}
 		if lag < 0 {
-			slog.Warn("negative lag", "namespace", endpoint, "eventHub", eventHub,
+			slog.Warn("negative lag", "namespace", hub.endpoint, "eventHub", eventHub,
 				"consumerGroup", consumerGroup, "partition", checkpoint.PartitionID)
 			lag = 0
 		}
 
 		lagSum += lag
-		s.metrics.RecordConsumerGroupPartitionLag(namespace, eventHub, consumerGroup, checkpoint.PartitionID, lag)
+		s.metrics.RecordConsumerGroupPartitionLag(namespace, eventHub, consumerGroup, checkpoint.PartitionID,
+			metrics.ProtocolEventHubs, lag)
+
+		if checkpoint.LastModified != nil {
+			s.metrics.RecordConsumerGroupPartitionCheckpointAge(namespace, eventHub, consumerGroup,
+				checkpoint.PartitionID, max(observedAt.Sub(*checkpoint.LastModified).Seconds(), 0))
+		}
+
+		key := partitionKey{namespace: namespace, eventHub: eventHub, consumerGroup: consumerGroup,
+			partitionID: checkpoint.PartitionID}
+		lagSeconds, ok := s.getLagSeconds(ctx, hub.consumerClient, key, seq, checkpoint.SequenceNumber, lag)
+		if ok {
+			hasLagSeconds = true
+			maxLagSeconds = max(maxLagSeconds, lagSeconds)
+			s.metrics.RecordConsumerGroupPartitionLagSeconds(namespace, eventHub, consumerGroup,
+				checkpoint.PartitionID, lagSeconds)
+		}
+
+		if checkpoint.SequenceNumber == nil {
+			continue
+		}
+		s.recordRetention(hub, consumerGroup, checkpoint.PartitionID, seq, *checkpoint.SequenceNumber)
+
+		stalledFor := s.checkpoints.stalledFor(key, *checkpoint.SequenceNumber, observedAt)
+		s.metrics.RecordConsumerGroupPartitionCheckpointStalled(namespace, eventHub, consumerGroup,
+			checkpoint.PartitionID, stalledFor.Seconds())
+		if lag > 0 && stalledFor >= s.cfg.StuckDuration {
+			stuck = true
+		}
+
+		consumeRate, ok := s.consumption.rate(key, *checkpoint.SequenceNumber, observedAt)
+		if !ok {
+			continue
+		}
+		s.metrics.RecordConsumerGroupPartitionConsumeRate(namespace, eventHub, consumerGroup,
+			checkpoint.PartitionID, consumeRate)
+		consumeRateSum += consumeRate
+		hasConsumeRate = true
+
+		if ingressRate, ok := hub.ingressRates[checkpoint.PartitionID]; ok {
+			lagGrowthRate := ingressRate - consumeRate
+			s.metrics.RecordConsumerGroupPartitionLagGrowthRate(namespace, eventHub, consumerGroup,
+				checkpoint.PartitionID, lagGrowthRate)
+			lagGrowthRateSum += lagGrowthRate
+			hasLagGrowthRate = true
+		}
 	}
 
-	s.metrics.RecordConsumerGroupLag(namespace, eventHub, consumerGroup, lagSum)
+	s.metrics.RecordConsumerGroupLag(namespace, eventHub, consumerGroup, metrics.ProtocolEventHubs, lagSum)
+	if hasLagSeconds {
+		s.metrics.RecordConsumerGroupLagSeconds(namespace, eventHub, consumerGroup, maxLagSeconds)
+	}
+	if hasConsumeRate {
+		s.metrics.RecordConsumerGroupConsumeRate(namespace, eventHub, consumerGroup, consumeRateSum)
+	}
+	if hasLagGrowthRate {
+		s.metrics.RecordConsumerGroupLagGrowthRate(namespace, eventHub, consumerGroup, lagGrowthRateSum)
+		s.metrics.RecordConsumerGroupCatchUpSeconds(namespace, eventHub, consumerGroup,
+			getCatchUpSeconds(lagSum, lagGrowthRateSum))
+	}
 	s.metrics.RecordConsumerGroupEvents(namespace, eventHub, consumerGroup, sequenceSum)
 
-	ownerships, err := blobStore.ListOwnership(ctx, endpoint, eventHub, consumerGroup, nil)
-	if err != nil {
-		return fmt.Errorf("failed to list ownership: %w", err)
+	return stuck, nil
+}
+
+// recordRetention records how many events are left before the checkpoint falls out of the partition's retention and
+// whether unprocessed events have already been lost.
+func (s *service) recordRetention(hub *eventHub, consumerGroup, partitionID string, seq eventhub.SequenceNumbers,
+	checkpointSequenceNumber int64) {
+
+	// the next event to be processed follows the checkpointed one
+	headroom := checkpointSequenceNumber + 1 - seq.Min
+	dataLoss := headroom < 0
+	if dataLoss {
+		slog.Warn("checkpoint fell out of retention", "namespace", hub.endpoint, "eventHub", hub.details.Name,
+			"consumerGroup", consumerGroup, "partition", partitionID, "lostEvents", -headroom)
+		headroom = 0
 	}
 
-	var activeOwnerships []azeventhubs.Ownership
+	s.metrics.RecordConsumerGroupPartitionRetention(hub.namespace, hub.details.Name, consumerGroup, partitionID,
+		headroom, dataLoss)
+}
 
-	for _, ownership := range ownerships {
-		expired := eventhub.IsOwnershipExpired(ownership, s.cfg.OwnershipExpirationDuration)
-		if !expired {
-			activeOwnerships = append(activeOwnerships, ownership)
-		}
-		s.metrics.RecordConsumerGroupPartitionOwner(namespace, eventHub, consumerGroup, ownership.PartitionID,
-			ownership.OwnerID, expired)
+// getLagSeconds returns the time between the last enqueued event of a partition and the checkpointed event.
+// It returns false if the lag can't be determined, e.g. because the checkpointed event already expired.
+func (s *service) getLagSeconds(ctx context.Context, consumerClient *azeventhubs.ConsumerClient, key partitionKey,
+	seq eventhub.SequenceNumbers, checkpointSequenceNumber *int64, lag int64) (float64, bool) {
+
+	if !s.cfg.LagTimeEnabled || checkpointSequenceNumber == nil || seq.LastEnqueuedOn.IsZero() {
+		return 0, false
 	}
 
-	s.metrics.RecordConsumerGroupOwners(namespace, eventHub, consumerGroup, len(activeOwnerships))
+	if lag == 0 {
+		return 0, true
+	}
 
-	state := "unstable"
-	if len(activeOwnerships) == partitionCount {
-		state = "stable"
-	} else if len(activeOwnerships) == 0 {
-		state = "empty"
+	if *checkpointSequenceNumber < seq.Min {
+		return 0, false
 	}
 
-	s.metrics.RecordConsumerGroupInfo(namespace, eventHub, consumerGroup, state)
-	return nil
+	enqueuedTime, ok := s.enqueuedTimes.get(key, *checkpointSequenceNumber)
+	if !ok {
+		var err error
+		enqueuedTime, err = s.enqueuedTime(ctx, consumerClient, key.partitionID, *checkpointSequenceNumber)
+		if err != nil {
+			s.metrics.RecordStageError(metrics.StageAMQP)
+			slog.Warn("failed to get enqueued time of checkpoint", "namespace", key.namespace,
+				"eventHub", key.eventHub, "consumerGroup", key.consumerGroup, "partition", key.partitionID,
+				"error", err)
+			return 0, false
+		}
+		s.enqueuedTimes.set(key, *checkpointSequenceNumber, enqueuedTime)
+	}
+
+	return max(seq.LastEnqueuedOn.Sub(enqueuedTime).Seconds(), 0), true
+}
+
+// getCatchUpSeconds estimates how long it takes to drain the lag at the current lag growth rate.
+// It returns noCatchUp if the lag isn't shrinking.
+func getCatchUpSeconds(lag int64, lagGrowthRate float64) float64 {
+	if lag == 0 {
+		return 0
+	}
+	if lagGrowthRate >= 0 {
+		return noCatchUp
+	}
+	return float64(lag) / -lagGrowthRate
 }
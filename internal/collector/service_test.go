@@ -0,0 +1,575 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+	"github.com/deviceinsight/eventhub-metrics/internal/config"
+	"github.com/deviceinsight/eventhub-metrics/internal/eventhub"
+	"github.com/deviceinsight/eventhub-metrics/internal/metrics"
+	"golang.org/x/sync/semaphore"
+)
+
+const testEndpoint = "my-ns.servicebus.windows.net"
+
+func TestProcessConsumerGroupRecordsLagAndState(t *testing.T) {
+	now := time.Now()
+	store := &memoryCheckpointStore{
+		checkpoints: []eventhub.Checkpoint{newCheckpoint("0", 5), newCheckpoint("1", 20)},
+		ownerships:  []azeventhubs.Ownership{newOwnership("0", "owner-a", now), newOwnership("1", "owner-b", now)},
+	}
+
+	recorder := newRecordingService()
+	s := newTestService(recorder, func() time.Time { return now })
+
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 10}, "1": {Max: 20}}, nil)
+	if err := s.processConsumerGroup(context.Background(), store, hub, "cg"); err != nil {
+		t.Fatalf("failed to process consumer group: %v", err)
+	}
+
+	assertRecorded(t, recorder, map[string]float64{
+		"consumer_group_partition_lag{consumer_group=cg,eh_namespace=my-ns,eventhub=eh,partition_id=0," +
+			"protocol=eventhubs}": 5,
+		"consumer_group_partition_lag{consumer_group=cg,eh_namespace=my-ns,eventhub=eh,partition_id=1," +
+			"protocol=eventhubs}": 0,
+		"consumer_group_lag{consumer_group=cg,eh_namespace=my-ns,eventhub=eh,protocol=eventhubs}":               5,
+		"consumer_group_events_sum{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":                           25,
+		"consumer_group_owners{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":                               2,
+		"consumer_group_distinct_owners{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":                      2,
+		"consumer_group_owner_imbalance_ratio{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":                1,
+		"consumer_group_info{consumer_group=cg,eh_namespace=my-ns,eventhub=eh,protocol=eventhubs,state=stable}": 1,
+	})
+}
+
+func TestProcessConsumerGroupDerivesRatesAcrossCycles(t *testing.T) {
+	now := time.Now()
+	store := &memoryCheckpointStore{checkpoints: []eventhub.Checkpoint{newCheckpoint("0", 100)}}
+
+	recorder := newRecordingService()
+	s := newTestService(recorder, func() time.Time { return now })
+
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 200}}, nil)
+	if err := s.processConsumerGroup(context.Background(), store, hub, "cg"); err != nil {
+		t.Fatalf("failed to process consumer group: %v", err)
+	}
+
+	// 10 seconds later the consumer group processed 300 events while 100 new events arrived
+	now = now.Add(10 * time.Second)
+	store.checkpoints = []eventhub.Checkpoint{newCheckpoint("0", 400)}
+	hub = newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 500}}, map[string]float64{"0": 10})
+
+	recorder.StartCycle()
+	if err := s.processConsumerGroup(context.Background(), store, hub, "cg"); err != nil {
+		t.Fatalf("failed to process consumer group: %v", err)
+	}
+
+	assertRecorded(t, recorder, map[string]float64{
+		"consumer_group_consume_rate{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":     30,
+		"consumer_group_lag_growth_rate{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":  -20,
+		"consumer_group_catch_up_seconds{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}": 5,
+	})
+}
+
+func TestGetCatchUpSeconds(t *testing.T) {
+	tests := []struct {
+		lag           int64
+		lagGrowthRate float64
+		expected      float64
+	}{
+		{lag: 0, lagGrowthRate: 5, expected: 0},
+		{lag: 100, lagGrowthRate: -20, expected: 5},
+		{lag: 100, lagGrowthRate: 0, expected: noCatchUp},
+		{lag: 100, lagGrowthRate: 10, expected: noCatchUp},
+	}
+
+	for _, test := range tests {
+		if catchUp := getCatchUpSeconds(test.lag, test.lagGrowthRate); catchUp != test.expected {
+			t.Errorf("expected %v for lag %d growing by %v, got %v", test.expected, test.lag, test.lagGrowthRate,
+				catchUp)
+		}
+	}
+}
+
+func TestProcessConsumerGroupRecordsRetention(t *testing.T) {
+	now := time.Now()
+	store := &memoryCheckpointStore{checkpoints: []eventhub.Checkpoint{newCheckpoint("0", 49), newCheckpoint("1", 9)}}
+
+	recorder := newRecordingService()
+	s := newTestService(recorder, func() time.Time { return now })
+
+	// the checkpoint of partition 1 fell out of retention, events 10 to 29 were never processed
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Min: 40, Max: 60}, "1": {Min: 30, Max: 60}},
+		nil)
+	if err := s.processConsumerGroup(context.Background(), store, hub, "cg"); err != nil {
+		t.Fatalf("failed to process consumer group: %v", err)
+	}
+
+	assertRecorded(t, recorder, map[string]float64{
+		"consumer_group_partition_retention_headroom{consumer_group=cg,eh_namespace=my-ns,eventhub=eh," +
+			"partition_id=0}": 10,
+		"consumer_group_partition_data_loss{consumer_group=cg,eh_namespace=my-ns,eventhub=eh,partition_id=0}": 0,
+		"consumer_group_partition_retention_headroom{consumer_group=cg,eh_namespace=my-ns,eventhub=eh," +
+			"partition_id=1}": 0,
+		"consumer_group_partition_data_loss{consumer_group=cg,eh_namespace=my-ns,eventhub=eh,partition_id=1}": 1,
+	})
+}
+
+func TestProcessConsumerGroupDetectsStuckConsumer(t *testing.T) {
+	now := time.Now()
+	store := &memoryCheckpointStore{checkpoints: []eventhub.Checkpoint{newCheckpoint("0", 5)}}
+
+	recorder := newRecordingService()
+	s := newTestService(recorder, func() time.Time { return now })
+
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 10}}, nil)
+	for range 2 {
+		store.ownerships = []azeventhubs.Ownership{newOwnership("0", "owner-a", now)}
+		recorder.StartCycle()
+		if err := s.processConsumerGroup(context.Background(), store, hub, "cg"); err != nil {
+			t.Fatalf("failed to process consumer group: %v", err)
+		}
+		now = now.Add(s.cfg.StuckDuration)
+	}
+
+	assertRecorded(t, recorder, map[string]float64{
+		"consumer_group_partition_checkpoint_stalled_seconds{consumer_group=cg,eh_namespace=my-ns,eventhub=eh," +
+			"partition_id=0}": s.cfg.StuckDuration.Seconds(),
+		"consumer_group_info{consumer_group=cg,eh_namespace=my-ns,eventhub=eh,protocol=eventhubs,state=stuck}": 0,
+	})
+}
+
+func TestProcessConsumerGroupRecordsCheckpointAge(t *testing.T) {
+	now := time.Now()
+	written := newCheckpoint("0", 5)
+	written.LastModified = to.Ptr(now.Add(-90 * time.Second))
+	store := &memoryCheckpointStore{checkpoints: []eventhub.Checkpoint{written, newCheckpoint("1", 5)}}
+
+	recorder := newRecordingService()
+	s := newTestService(recorder, func() time.Time { return now })
+
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 10}, "1": {Max: 10}}, nil)
+	if err := s.processConsumerGroup(context.Background(), store, hub, "cg"); err != nil {
+		t.Fatalf("failed to process consumer group: %v", err)
+	}
+
+	assertRecorded(t, recorder, map[string]float64{
+		"consumer_group_partition_checkpoint_age_seconds{consumer_group=cg,eh_namespace=my-ns,eventhub=eh," +
+			"partition_id=0}": 90,
+	})
+
+	// the age is unknown for checkpoint stores which don't provide the modification time
+	if _, ok := recorder.values["consumer_group_partition_checkpoint_age_seconds{consumer_group=cg,eh_namespace=my-ns,"+
+		"eventhub=eh,partition_id=1}"]; ok {
+		t.Error("expected no checkpoint age without modification time")
+	}
+}
+
+func TestProcessConsumerGroupDetectsOwnershipChanges(t *testing.T) {
+	now := time.Now()
+	store := &memoryCheckpointStore{}
+
+	recorder := newRecordingService()
+	s := newTestService(recorder, func() time.Time { return now })
+
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 10}}, nil)
+	for _, owner := range []string{"owner-a", "owner-b"} {
+		store.ownerships = []azeventhubs.Ownership{newOwnership("0", owner, now)}
+		recorder.StartCycle()
+		if err := s.processConsumerGroup(context.Background(), store, hub, "cg"); err != nil {
+			t.Fatalf("failed to process consumer group: %v", err)
+		}
+	}
+
+	assertRecorded(t, recorder, map[string]float64{
+		"consumer_group_partition_ownership_changes_total{consumer_group=cg,eh_namespace=my-ns,eventhub=eh," +
+			"partition_id=0}": 1,
+		"consumer_group_partition_ownership_last_change_timestamp_seconds{consumer_group=cg,eh_namespace=my-ns," +
+			"eventhub=eh,partition_id=0}": float64(now.Unix()),
+		"consumer_group_info{consumer_group=cg,eh_namespace=my-ns,eventhub=eh,protocol=eventhubs,state=rebalancing}": 0,
+	})
+}
+
+func TestGetLagSeconds(t *testing.T) {
+	now := time.Now()
+	s := newTestService(newRecordingService(), func() time.Time { return now })
+
+	receives := 0
+	s.enqueuedTime = func(_ context.Context, _ *azeventhubs.ConsumerClient, partitionID string,
+		_ int64) (time.Time, error) {
+
+		receives++
+		if partitionID == "1" {
+			return time.Time{}, errors.New("receiver with higher epoch exists")
+		}
+		return now.Add(-30 * time.Second), nil
+	}
+
+	key := partitionKey{namespace: "my-ns", eventHub: "eh", consumerGroup: "cg", partitionID: "0"}
+	seq := eventhub.SequenceNumbers{Min: 10, Max: 100, LastEnqueuedOn: now}
+
+	if _, ok := s.getLagSeconds(context.Background(), nil, key, seq, to.Ptr(int64(50)), 50); ok || receives != 0 {
+		t.Fatalf("expected no lag in seconds while disabled, got %t after %d receives", ok, receives)
+	}
+
+	s.cfg.LagTimeEnabled = true
+
+	tests := []struct {
+		name       string
+		partition  string
+		checkpoint *int64
+		lag        int64
+		expected   float64
+		ok         bool
+	}{
+		{name: "without checkpoint", partition: "0", lag: 100},
+		{name: "without lag", partition: "0", checkpoint: to.Ptr(int64(100)), ok: true},
+		{name: "expired checkpoint", partition: "0", checkpoint: to.Ptr(int64(5)), lag: 95},
+		{name: "lagging", partition: "0", checkpoint: to.Ptr(int64(50)), lag: 50, expected: 30, ok: true},
+		{name: "cached", partition: "0", checkpoint: to.Ptr(int64(50)), lag: 50, expected: 30, ok: true},
+		{name: "failed receive", partition: "1", checkpoint: to.Ptr(int64(50)), lag: 50},
+	}
+
+	for _, test := range tests {
+		key.partitionID = test.partition
+		lagSeconds, ok := s.getLagSeconds(context.Background(), nil, key, seq, test.checkpoint, test.lag)
+		if ok != test.ok || lagSeconds != test.expected {
+			t.Errorf("%s: expected %v (%t), got %v (%t)", test.name, test.expected, test.ok, lagSeconds, ok)
+		}
+	}
+
+	// only the lagging partitions are received, the second lookup of partition 0 is cached
+	if receives != 2 {
+		t.Fatalf("expected 2 receives, got %d", receives)
+	}
+}
+
+func TestRetainConsumerGroupsForgetsRemovedConsumerGroups(t *testing.T) {
+	now := time.Now()
+	s := newTestService(newRecordingService(), func() time.Time { return now })
+
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 10}}, nil)
+	for _, consumerGroup := range []string{"cg", "removed"} {
+		store := &memoryCheckpointStore{
+			checkpoints: []eventhub.Checkpoint{newCheckpoint("0", 5)},
+			ownerships:  []azeventhubs.Ownership{newOwnership("0", "owner-a", now)},
+		}
+		if err := s.processConsumerGroup(context.Background(), store, hub, consumerGroup); err != nil {
+			t.Fatalf("failed to process consumer group: %v", err)
+		}
+	}
+	ingressKey := partitionKey{namespace: "my-ns", eventHub: "eh", partitionID: "0"}
+	s.ingress.rate(ingressKey, 10, now)
+
+	s.retainConsumerGroups("my-ns", "eh", []string{"cg"})
+
+	removed := partitionKey{namespace: "my-ns", eventHub: "eh", consumerGroup: "removed", partitionID: "0"}
+	retained := partitionKey{namespace: "my-ns", eventHub: "eh", consumerGroup: "cg", partitionID: "0"}
+
+	for name, entries := range map[string]map[partitionKey]bool{
+		"consumption": keysOf(s.consumption.samples),
+		"checkpoints": keysOf(s.checkpoints.progress),
+		"owners":      keysOf(s.owners.owners),
+	} {
+		if entries[removed] || !entries[retained] {
+			t.Errorf("expected %s to only retain the existing consumer group, got %v", name, entries)
+		}
+	}
+	if _, ok := s.ingress.samples[ingressKey]; !ok {
+		t.Error("expected the ingress of the eventhub to be retained")
+	}
+}
+
+func keysOf[V any](entries map[partitionKey]V) map[partitionKey]bool {
+	keys := make(map[partitionKey]bool, len(entries))
+	for key := range entries {
+		keys[key] = true
+	}
+	return keys
+}
+
+func TestProcessConsumerGroupIgnoresExpiredOwnerships(t *testing.T) {
+	now := time.Now()
+	store := &memoryCheckpointStore{ownerships: []azeventhubs.Ownership{
+		newOwnership("0", "owner-a", now),
+		newOwnership("1", "owner-b", now.Add(-2*time.Minute)),
+	}}
+
+	recorder := newRecordingService()
+	s := newTestService(recorder, func() time.Time { return now })
+
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 10}, "1": {Max: 10}}, nil)
+	if err := s.processConsumerGroup(context.Background(), store, hub, "cg"); err != nil {
+		t.Fatalf("failed to process consumer group: %v", err)
+	}
+
+	assertRecorded(t, recorder, map[string]float64{
+		"consumer_group_owners{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":                                 1,
+		"consumer_group_distinct_owners{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":                        1,
+		"consumer_group_owner_partitions_max{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":                   1,
+		"consumer_group_info{consumer_group=cg,eh_namespace=my-ns,eventhub=eh,protocol=eventhubs,state=unstable}": 0,
+		"consumer_group_owner_imbalance_ratio{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":                  1,
+	})
+
+	// once all ownerships expired the consumer group is empty
+	store.ownerships[0].LastModifiedTime = now.Add(-2 * time.Minute)
+	recorder.StartCycle()
+	if err := s.processConsumerGroup(context.Background(), store, hub, "cg"); err != nil {
+		t.Fatalf("failed to process consumer group: %v", err)
+	}
+
+	assertRecorded(t, recorder, map[string]float64{
+		"consumer_group_owners{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":                              0,
+		"consumer_group_distinct_owners{consumer_group=cg,eh_namespace=my-ns,eventhub=eh}":                     0,
+		"consumer_group_info{consumer_group=cg,eh_namespace=my-ns,eventhub=eh,protocol=eventhubs,state=empty}": 0,
+	})
+}
+
+func TestGetOwnerDistribution(t *testing.T) {
+	now := time.Now()
+	var ownerships []azeventhubs.Ownership
+	for i, owner := range []string{"a", "a", "a", "b"} {
+		ownerships = append(ownerships, newOwnership(string(rune('0'+i)), owner, now))
+	}
+
+	distribution := getOwnerDistribution(ownerships)
+
+	expected := ownerDistribution{owners: 2, maxPartitions: 3, minPartitions: 1, imbalanceRatio: 1.5}
+	if distribution != expected {
+		t.Fatalf("expected %+v, got %+v", expected, distribution)
+	}
+}
+
+func TestLimitedCheckpointStoreBoundsRequests(t *testing.T) {
+	store := &countingCheckpointStore{}
+	limited := newLimitedCheckpointStore(store, semaphore.NewWeighted(2))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := limited.ListCheckpoints(context.Background(), testEndpoint, "eh", "cg"); err != nil {
+				t.Errorf("failed to list checkpoints: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight := store.maxInFlight.Load(); maxInFlight > 2 {
+		t.Fatalf("expected at most 2 requests in flight, got %d", maxInFlight)
+	}
+}
+
+func TestProcessConsumerGroupsCountsCheckpointErrors(t *testing.T) {
+	recorder := newRecordingService()
+	s := newTestService(recorder, time.Now)
+	stores := map[string]eventhub.CheckpointStore{"cg": &failingCheckpointStore{}}
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 10}}, nil)
+
+	if err := s.processConsumerGroups(context.Background(), stores, hub, []string{"cg"}, nil); err == nil {
+		t.Fatal("expected the failed consumer group to be returned")
+	}
+
+	// the errors of a cycle which exceeded its timeout aren't checkpoint errors
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.processConsumerGroups(ctx, stores, hub, []string{"cg"}, nil); err == nil {
+		t.Fatal("expected the failed consumer group to be returned")
+	}
+
+	s.metrics.RecordCollectionCycle(time.Second, true)
+	assertRecorded(t, recorder, map[string]float64{
+		"collection_stage_errors{stage=checkpoint}": 1,
+		"collection_stage_errors{stage=blob_list}":  0,
+	})
+}
+
+func TestProcessConsumerGroupsLimitsConcurrency(t *testing.T) {
+	s := newTestService(newRecordingService(), time.Now)
+	s.cfg.ConsumerGroupConcurrency = 2
+
+	store := &countingCheckpointStore{}
+	consumerGroups := []string{"cg-1", "cg-2", "cg-3", "cg-4", "cg-5", "cg-6"}
+	stores := make(map[string]eventhub.CheckpointStore)
+	for _, consumerGroup := range consumerGroups {
+		stores[consumerGroup] = store
+	}
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 10}}, nil)
+
+	if err := s.processConsumerGroups(context.Background(), stores, hub, consumerGroups, nil); err != nil {
+		t.Fatalf("failed to process consumer groups: %v", err)
+	}
+
+	if maxInFlight := store.maxInFlight.Load(); maxInFlight > 2 {
+		t.Fatalf("expected at most 2 consumer groups in flight, got %d", maxInFlight)
+	}
+}
+
+func TestProcessConsumerGroupsIsNotBlockedBySlowConsumerGroup(t *testing.T) {
+	s := newTestService(newRecordingService(), time.Now)
+	s.cfg.ConsumerGroupConcurrency = 2
+
+	release := make(chan struct{})
+	listed := make(chan string, 3)
+	fastStore := &signallingCheckpointStore{listed: listed}
+	stores := map[string]eventhub.CheckpointStore{
+		"slow": &blockingCheckpointStore{release: release},
+		"cg-1": fastStore,
+		"cg-2": fastStore,
+		"cg-3": fastStore,
+	}
+	hub := newTestEventHub(map[string]eventhub.SequenceNumbers{"0": {Max: 10}}, nil)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.processConsumerGroups(context.Background(), stores, hub, []string{"slow", "cg-1", "cg-2", "cg-3"},
+			nil)
+	}()
+
+	for range 3 {
+		select {
+		case <-listed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the other consumer groups to be processed while the slow one is blocked")
+		}
+	}
+
+	close(release)
+	if err := <-errs; err != nil {
+		t.Fatalf("failed to process consumer groups: %v", err)
+	}
+}
+
+// blockingCheckpointStore lists the checkpoints once it is released.
+type blockingCheckpointStore struct {
+	memoryCheckpointStore
+	release <-chan struct{}
+}
+
+func (s *blockingCheckpointStore) ListCheckpoints(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]eventhub.Checkpoint, error) {
+
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return s.memoryCheckpointStore.ListCheckpoints(ctx, namespace, eventHub, consumerGroup)
+}
+
+// signallingCheckpointStore reports every consumer group whose ownerships were listed.
+type signallingCheckpointStore struct {
+	memoryCheckpointStore
+	listed chan<- string
+}
+
+func (s *signallingCheckpointStore) ListOwnership(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]azeventhubs.Ownership, error) {
+
+	s.listed <- consumerGroup
+	return s.memoryCheckpointStore.ListOwnership(ctx, namespace, eventHub, consumerGroup)
+}
+
+// failingCheckpointStore fails to list the checkpoints.
+type failingCheckpointStore struct {
+	memoryCheckpointStore
+}
+
+func (*failingCheckpointStore) ListCheckpoints(context.Context, string, string, string) ([]eventhub.Checkpoint,
+	error) {
+	return nil, errors.New("unavailable")
+}
+
+// countingCheckpointStore tracks how many requests are in flight at the same time.
+type countingCheckpointStore struct {
+	memoryCheckpointStore
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (s *countingCheckpointStore) ListCheckpoints(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]eventhub.Checkpoint, error) {
+
+	inFlight := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
+	for {
+		maxInFlight := s.maxInFlight.Load()
+		if inFlight <= maxInFlight || s.maxInFlight.CompareAndSwap(maxInFlight, inFlight) {
+			break
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	return s.memoryCheckpointStore.ListCheckpoints(ctx, namespace, eventHub, consumerGroup)
+}
+
+// memoryCheckpointStore is an in-memory eventhub.CheckpointStore.
+type memoryCheckpointStore struct {
+	checkpoints []eventhub.Checkpoint
+	ownerships  []azeventhubs.Ownership
+}
+
+func (s *memoryCheckpointStore) ListCheckpoints(context.Context, string, string,
+	string) ([]eventhub.Checkpoint, error) {
+	return s.checkpoints, nil
+}
+
+func (s *memoryCheckpointStore) ListOwnership(context.Context, string, string,
+	string) ([]azeventhubs.Ownership, error) {
+	return s.ownerships, nil
+}
+
+func newTestService(recorder *recordingService, now func() time.Time) *service {
+	s, ok := NewService(metrics.NewDelegateService(recorder), config.CollectorConfig{
+		OwnershipExpirationDuration: time.Minute,
+		StuckDuration:               5 * time.Minute,
+	}, nil).(*service)
+	if !ok {
+		panic("NewService did not return *service")
+	}
+	s.now = now
+	return s
+}
+
+func newTestEventHub(sequenceNumbers map[string]eventhub.SequenceNumbers,
+	ingressRates map[string]float64) *eventHub {
+
+	if ingressRates == nil {
+		ingressRates = make(map[string]float64)
+	}
+
+	return &eventHub{
+		namespace:       "my-ns",
+		endpoint:        testEndpoint,
+		details:         &eventhub.Details{Name: "eh", PartitionCount: len(sequenceNumbers)},
+		sequenceNumbers: sequenceNumbers,
+		ingressRates:    ingressRates,
+	}
+}
+
+func newCheckpoint(partitionID string, sequenceNumber int64) eventhub.Checkpoint {
+	return eventhub.Checkpoint{Checkpoint: azeventhubs.Checkpoint{
+		PartitionID:    partitionID,
+		SequenceNumber: to.Ptr(sequenceNumber),
+	}}
+}
+
+func newOwnership(partitionID, ownerID string, lastModified time.Time) azeventhubs.Ownership {
+	return azeventhubs.Ownership{PartitionID: partitionID, OwnerID: ownerID, LastModifiedTime: lastModified}
+}
+
+func assertRecorded(t *testing.T, recorder *recordingService, expected map[string]float64) {
+	t.Helper()
+	for key, value := range expected {
+		if got, ok := recorder.values[key]; !ok || got != value {
+			t.Errorf("expected %s to be %v, got %v (recorded: %t)", key, value, got, ok)
+		}
+	}
+}
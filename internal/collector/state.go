@@ -0,0 +1,175 @@
+package collector
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// partitionKey identifies a partition as seen by a single consumer group.
+type partitionKey struct {
+	namespace     string
+	eventHub      string
+	consumerGroup string
+	partitionID   string
+}
+
+// retainKeys removes the entries of all partitions which aren't kept, e.g. of deleted eventhubs or consumer groups.
+func retainKeys[V any](entries map[partitionKey]V, keep func(partitionKey) bool) {
+	maps.DeleteFunc(entries, func(key partitionKey, _ V) bool { return !keep(key) })
+}
+
+type enqueuedTimeEntry struct {
+	sequenceNumber int64
+	enqueuedTime   time.Time
+}
+
+// enqueuedTimeCache remembers the enqueued time of the last checkpointed event per partition, so an event only has
+// to be received again once the checkpoint moved.
+type enqueuedTimeCache struct {
+	mu      sync.Mutex
+	entries map[partitionKey]enqueuedTimeEntry
+}
+
+func newEnqueuedTimeCache() *enqueuedTimeCache {
+	return &enqueuedTimeCache{entries: make(map[partitionKey]enqueuedTimeEntry)}
+}
+
+func (c *enqueuedTimeCache) get(key partitionKey, sequenceNumber int64) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.sequenceNumber != sequenceNumber {
+		return time.Time{}, false
+	}
+	return entry.enqueuedTime, true
+}
+
+func (c *enqueuedTimeCache) set(key partitionKey, sequenceNumber int64, enqueuedTime time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = enqueuedTimeEntry{sequenceNumber: sequenceNumber, enqueuedTime: enqueuedTime}
+}
+
+func (c *enqueuedTimeCache) retain(keep func(partitionKey) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	retainKeys(c.entries, keep)
+}
+
+type sample struct {
+	value      int64
+	observedAt time.Time
+}
+
+// rateTracker keeps the previous cycle's sequence numbers to derive per-second rates across collection cycles.
+type rateTracker struct {
+	mu      sync.Mutex
+	samples map[partitionKey]sample
+}
+
+func newRateTracker() *rateTracker {
+	return &rateTracker{samples: make(map[partitionKey]sample)}
+}
+
+// rate stores the observed value and returns its change per second since the previous observation.
+// It returns false for the first observation and if the value went backwards, e.g. after an eventhub was recreated.
+func (t *rateTracker) rate(key partitionKey, value int64, observedAt time.Time) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, ok := t.samples[key]
+	t.samples[key] = sample{value: value, observedAt: observedAt}
+
+	elapsed := observedAt.Sub(previous.observedAt).Seconds()
+	if !ok || elapsed <= 0 || value < previous.value {
+		return 0, false
+	}
+
+	return float64(value-previous.value) / elapsed, true
+}
+
+func (t *rateTracker) retain(keep func(partitionKey) bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	retainKeys(t.samples, keep)
+}
+
+type checkpointProgress struct {
+	sequenceNumber int64
+	advancedAt     time.Time
+}
+
+// checkpointTracker remembers when the checkpoint of each partition last advanced.
+type checkpointTracker struct {
+	mu       sync.Mutex
+	progress map[partitionKey]checkpointProgress
+}
+
+func newCheckpointTracker() *checkpointTracker {
+	return &checkpointTracker{progress: make(map[partitionKey]checkpointProgress)}
+}
+
+// stalledFor stores the observed checkpoint and returns for how long it hasn't advanced.
+func (t *checkpointTracker) stalledFor(key partitionKey, sequenceNumber int64, observedAt time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, ok := t.progress[key]
+	if !ok || previous.sequenceNumber != sequenceNumber {
+		t.progress[key] = checkpointProgress{sequenceNumber: sequenceNumber, advancedAt: observedAt}
+		return 0
+	}
+
+	return observedAt.Sub(previous.advancedAt)
+}
+
+func (t *checkpointTracker) retain(keep func(partitionKey) bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	retainKeys(t.progress, keep)
+}
+
+type ownerHistory struct {
+	ownerID      string
+	changes      int64
+	lastChangeAt time.Time
+}
+
+// ownerTracker remembers the last owner of each partition to detect ownership changes across collection cycles.
+type ownerTracker struct {
+	mu     sync.Mutex
+	owners map[partitionKey]ownerHistory
+}
+
+func newOwnerTracker() *ownerTracker {
+	return &ownerTracker{owners: make(map[partitionKey]ownerHistory)}
+}
+
+// observe stores the observed owner and returns the partition's ownership history and whether a different owner held
+// the partition before. Relinquished ownerships without an owner are ignored, so a handover is only counted once.
+func (t *ownerTracker) observe(key partitionKey, ownerID string, observedAt time.Time) (ownerHistory, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	history, ok := t.owners[key]
+	if ownerID == "" {
+		return history, false
+	}
+
+	changed := ok && history.ownerID != ownerID
+	if changed {
+		history.changes++
+		history.lastChangeAt = observedAt
+	}
+	history.ownerID = ownerID
+	t.owners[key] = history
+
+	return history, changed
+}
+
+func (t *ownerTracker) retain(keep func(partitionKey) bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	retainKeys(t.owners, keep)
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateTracker(t *testing.T) {
+	now := time.Now()
+	tracker := newRateTracker()
+	key := partitionKey{namespace: "my-ns", eventHub: "eh", partitionID: "0"}
+
+	if _, ok := tracker.rate(key, 100, now); ok {
+		t.Fatal("expected no rate for the first observation")
+	}
+
+	if rate, ok := tracker.rate(key, 300, now.Add(10*time.Second)); !ok || rate != 20 {
+		t.Fatalf("expected a rate of 20, got %v (%t)", rate, ok)
+	}
+
+	// the eventhub was recreated and its sequence numbers started over
+	if _, ok := tracker.rate(key, 50, now.Add(20*time.Second)); ok {
+		t.Fatal("expected no rate for a value which went backwards")
+	}
+
+	if rate, ok := tracker.rate(key, 50, now.Add(30*time.Second)); !ok || rate != 0 {
+		t.Fatalf("expected a rate of 0, got %v (%t)", rate, ok)
+	}
+}
+
+func TestCheckpointTracker(t *testing.T) {
+	now := time.Now()
+	tracker := newCheckpointTracker()
+	key := partitionKey{namespace: "my-ns", eventHub: "eh", consumerGroup: "cg", partitionID: "0"}
+
+	if stalledFor := tracker.stalledFor(key, 10, now); stalledFor != 0 {
+		t.Fatalf("expected a new checkpoint not to be stalled, got %s", stalledFor)
+	}
+
+	if stalledFor := tracker.stalledFor(key, 10, now.Add(time.Minute)); stalledFor != time.Minute {
+		t.Fatalf("expected the checkpoint to be stalled for a minute, got %s", stalledFor)
+	}
+
+	if stalledFor := tracker.stalledFor(key, 20, now.Add(2*time.Minute)); stalledFor != 0 {
+		t.Fatalf("expected an advanced checkpoint not to be stalled, got %s", stalledFor)
+	}
+}
+
+func TestOwnerTracker(t *testing.T) {
+	now := time.Now()
+	tracker := newOwnerTracker()
+	key := partitionKey{namespace: "my-ns", eventHub: "eh", consumerGroup: "cg", partitionID: "0"}
+
+	if _, changed := tracker.observe(key, "owner-a", now); changed {
+		t.Fatal("expected the first owner not to be a change")
+	}
+
+	// the owner relinquished the partition before owner-b claimed it, which is a single handover
+	if _, changed := tracker.observe(key, "", now.Add(time.Minute)); changed {
+		t.Fatal("expected a relinquished ownership not to be a change")
+	}
+
+	history, changed := tracker.observe(key, "owner-b", now.Add(2*time.Minute))
+	if !changed || history.changes != 1 || !history.lastChangeAt.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("expected a single handover, got %+v (%t)", history, changed)
+	}
+
+	if history, changed := tracker.observe(key, "owner-b", now.Add(3*time.Minute)); changed || history.changes != 1 {
+		t.Fatalf("expected the same owner not to be a change, got %+v (%t)", history, changed)
+	}
+}
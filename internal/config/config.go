@@ -19,12 +19,36 @@ type NamespaceConfig struct {
 	IncludedEventHubs      string
 	ExcludedEventHubs      string
 	ExcludedConsumerGroups string
+	Kafka                  KafkaConfig
+	CheckpointStores       []CheckpointStoreConfig
+}
+
+// CheckpointStoreConfig maps a consumer group to the storage container which stores its checkpoints.
+type CheckpointStoreConfig struct {
+	EventHub      string
+	ConsumerGroup string
+	Endpoint      string
+	Container     string
+	Prefix        string
+	Legacy        bool
+}
+
+type KafkaConfig struct {
+	Enabled bool
 }
 
 type BlobStorageConfig struct {
 	Endpoint           string
 	IncludedContainers string
 	ExcludedContainers string
+	LegacyNamespace    string
+	LegacyPrefix       string
+}
+
+type TableStorageConfig struct {
+	Endpoint       string
+	IncludedTables string
+	ExcludedTables string
 }
 
 type AppInsightsConfig struct {
@@ -47,6 +71,8 @@ type OtlpConfig struct {
 	Enabled  bool
 	BaseURL  string
 	Protocol string
+	// Tracing exports spans of the collection cycles to the same endpoint.
+	Tracing bool
 }
 
 type ExporterConfig struct {
@@ -59,6 +85,9 @@ type ExporterConfig struct {
 type ServerConfig struct {
 	Address     string
 	ReadTimeout time.Duration
+	// LivenessIntervals is the number of collector intervals without a completed cycle after which /live fails, in
+	// addition to the cycle timeout and its grace period.
+	LivenessIntervals int
 }
 
 type CollectorConfig struct {
@@ -66,6 +95,23 @@ type CollectorConfig struct {
 	Concurrency                 int
 	Interval                    *time.Duration
 	ExitOnAuthenticationError   bool
+	LagTimeEnabled              bool
+	LagTimeConsumerGroup        string
+	StuckDuration               time.Duration
+	DiscoveryInterval           time.Duration
+	PartitionConcurrency        int
+	ConsumerGroupConcurrency    int
+	MaxStorageRequests          int
+	CycleTimeout                time.Duration
+	Requests                    RequestConfig
+}
+
+// RequestConfig configures the requests to the management endpoints of the namespaces.
+type RequestConfig struct {
+	Timeout        time.Duration
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
 }
 
 type LogConfig struct {
@@ -74,12 +120,13 @@ type LogConfig struct {
 }
 
 type Config struct {
-	Namespaces      []NamespaceConfig
-	StorageAccounts []BlobStorageConfig
-	Server          ServerConfig
-	Exporter        ExporterConfig
-	Collector       CollectorConfig
-	Log             LogConfig
+	Namespaces           []NamespaceConfig
+	StorageAccounts      []BlobStorageConfig
+	TableStorageAccounts []TableStorageConfig
+	Server               ServerConfig
+	Exporter             ExporterConfig
+	Collector            CollectorConfig
+	Log                  LogConfig
 }
 
 const EnvPrefix string = "EH_METRICS_"
@@ -101,8 +148,21 @@ func Load() (*Config, error) {
 		"collector.ownershipExpirationDuration": time.Minute,
 		"collector.concurrency":                 8, //nolint:mnd // just a default
 		"collector.exitOnAuthenticationError":   true,
+		"collector.lagTimeEnabled":              false,
+		"collector.lagTimeConsumerGroup":        "$Default",
+		"collector.stuckDuration":               5 * time.Minute,
+		"collector.discoveryInterval":           10 * time.Minute,
+		"collector.partitionConcurrency":        4,  //nolint:mnd // just a default
+		"collector.consumerGroupConcurrency":    4,  //nolint:mnd // just a default
+		"collector.maxStorageRequests":          16, //nolint:mnd // just a default
+		"collector.cycleTimeout":                10 * time.Minute,
+		"collector.requests.timeout":            30 * time.Second,
+		"collector.requests.maxRetries":         3, //nolint:mnd // just a default
+		"collector.requests.initialBackoff":     500 * time.Millisecond,
+		"collector.requests.maxBackoff":         30 * time.Second,
 		"server.address":                        ":8080",
 		"server.readTimeout":                    "1s",
+		"server.livenessIntervals":              3, //nolint:mnd // just a default
 		"exporter.otlp.protocol":                "grpc",
 	}, "."), nil); err != nil {
 		return nil, fmt.Errorf("failed to load config defaults: %w", err)
@@ -0,0 +1,22 @@
+package eventhub
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+)
+
+// Checkpoint is the last event a consumer group processed on a partition.
+type Checkpoint struct {
+	azeventhubs.Checkpoint
+	// LastModified is the time the checkpoint was last written, if the checkpoint store tracks it.
+	LastModified *time.Time
+}
+
+// CheckpointStore is a source of the checkpoints and ownerships which the processors of consumer groups persist.
+// namespace is the fully qualified namespace of the eventhub.
+type CheckpointStore interface {
+	ListCheckpoints(ctx context.Context, namespace, eventHub, consumerGroup string) ([]Checkpoint, error)
+	ListOwnership(ctx context.Context, namespace, eventHub, consumerGroup string) ([]azeventhubs.Ownership, error)
+}
@@ -5,16 +5,22 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/deviceinsight/eventhub-metrics/internal/rest"
+	"github.com/deviceinsight/eventhub-metrics/internal/tracing"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+	"golang.org/x/sync/errgroup"
 )
 
 const closeTimeout = 10 * time.Second
 
+// receiveTimeout bounds how long we wait for a single event when looking up its enqueued time.
+const receiveTimeout = 10 * time.Second
+
 type Details struct {
 	Name                   string
 	PartitionCount         int
@@ -23,8 +29,9 @@ type Details struct {
 }
 
 type SequenceNumbers struct {
-	Min int64
-	Max int64
+	Min            int64
+	Max            int64
+	LastEnqueuedOn time.Time
 }
 
 func GetEventHubs(ctx context.Context, credential *azidentity.DefaultAzureCredential,
@@ -84,46 +91,142 @@ func GetConsumerGroups(ctx context.Context, credential *azidentity.DefaultAzureC
 	return consumerGroups, nil
 }
 
-func GetSequenceNumbers(ctx context.Context, credential *azidentity.DefaultAzureCredential,
-	endpoint string, eventhubDetails *Details) (map[string]SequenceNumbers, error) {
+// NewConsumerClient opens an AMQP consumer client for the given event hub and consumer group. The consumer group is
+// only used to receive events. The client must be released with CloseConsumerClient.
+func NewConsumerClient(credential *azidentity.DefaultAzureCredential, endpoint, eventHub,
+	consumerGroup string) (*azeventhubs.ConsumerClient, error) {
 
 	eventhubURL, err := rest.GetURL(endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse eventhub url: %w", err)
 	}
 
-	consumerClient, err := azeventhubs.NewConsumerClient(eventhubURL.Hostname(), eventhubDetails.Name,
-		azeventhubs.DefaultConsumerGroup, credential, nil)
+	consumerClient, err := azeventhubs.NewConsumerClient(eventhubURL.Hostname(), eventHub, consumerGroup,
+		credential, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create consumer client: %w", err)
 	}
+	return consumerClient, nil
+}
+
+func CloseConsumerClient(consumerClient *azeventhubs.ConsumerClient, eventHub string) {
+	// use a background context with a short timeout so a canceled ctx
+	// does not prevent the client from releasing its AMQP connection,
+	// links and background goroutines.
+	closeCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	if cerr := consumerClient.Close(closeCtx); cerr != nil {
+		slog.Warn("failed to close eventhub consumer client",
+			"eventhub", eventHub, "error", cerr)
+	}
+}
+
+// GetSequenceNumbers queries the properties of all partitions of an eventhub, at most concurrency at a time. The
+// management link only supports querying a single partition per request.
+func GetSequenceNumbers(ctx context.Context, consumerClient *azeventhubs.ConsumerClient,
+	eventhubDetails *Details, concurrency int) (map[string]SequenceNumbers, error) {
+
+	ctx, span := tracing.Start(ctx, "eventhub.GetSequenceNumbers", tracing.AttributeEventHub.String(eventhubDetails.Name))
+	sequenceNumbers, err := getSequenceNumbers(ctx, consumerClient, eventhubDetails, concurrency)
+	tracing.End(span, err)
+	return sequenceNumbers, err
+}
+
+// partitionPropertiesClient is the part of azeventhubs.ConsumerClient which queries the partitions.
+type partitionPropertiesClient interface {
+	GetPartitionProperties(ctx context.Context, partitionID string,
+		options *azeventhubs.GetPartitionPropertiesOptions) (azeventhubs.PartitionProperties, error)
+}
+
+func getSequenceNumbers(ctx context.Context, consumerClient partitionPropertiesClient, eventhubDetails *Details,
+	concurrency int) (map[string]SequenceNumbers, error) {
+
+	var mu sync.Mutex
+	lastEnqueuedSequenceNumbers := make(map[string]SequenceNumbers)
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(max(concurrency, 1))
+
+	for _, partitionID := range eventhubDetails.PartitionIDs {
+		g.Go(func() error {
+			// don't query the remaining partitions once a partition failed
+			if err := gCtx.Err(); err != nil {
+				return err
+			}
+
+			partitionCtx, partitionSpan := tracing.Start(gCtx, "eventhub.GetPartitionProperties",
+				tracing.AttributePartitionID.String(partitionID))
+			partitionProps, err := consumerClient.GetPartitionProperties(partitionCtx, partitionID, nil)
+			tracing.End(partitionSpan, err)
+			if err != nil {
+				return fmt.Errorf("failed to get partition properties: %w", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			lastEnqueuedSequenceNumbers[partitionID] = SequenceNumbers{
+				Min:            partitionProps.BeginningSequenceNumber,
+				Max:            partitionProps.LastEnqueuedSequenceNumber,
+				LastEnqueuedOn: partitionProps.LastEnqueuedOn,
+			}
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return lastEnqueuedSequenceNumbers, nil
+}
+
+// GetEnqueuedTime receives the single event with the given sequence number from a partition and returns the time
+// it was enqueued. The event must still be within the retention period of the event hub.
+func GetEnqueuedTime(ctx context.Context, consumerClient *azeventhubs.ConsumerClient, partitionID string,
+	sequenceNumber int64) (time.Time, error) {
+
+	ctx, span := tracing.Start(ctx, "eventhub.GetEnqueuedTime", tracing.AttributePartitionID.String(partitionID))
+	enqueuedTime, err := getEnqueuedTime(ctx, consumerClient, partitionID, sequenceNumber)
+	tracing.End(span, err)
+	return enqueuedTime, err
+}
+
+func getEnqueuedTime(ctx context.Context, consumerClient *azeventhubs.ConsumerClient, partitionID string,
+	sequenceNumber int64) (time.Time, error) {
+
+	partitionClient, err := consumerClient.NewPartitionClient(partitionID, &azeventhubs.PartitionClientOptions{
+		StartPosition: azeventhubs.StartPosition{SequenceNumber: &sequenceNumber, Inclusive: true},
+		Prefetch:      -1,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to create partition client: %w", err)
+	}
 	defer func() {
-		// use a background context with a short timeout so a canceled ctx
-		// does not prevent the client from releasing its AMQP connection,
-		// links and background goroutines.
 		closeCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
 		defer cancel()
-		if cerr := consumerClient.Close(closeCtx); cerr != nil {
-			slog.Warn("failed to close eventhub consumer client",
-				"eventhub", eventhubDetails.Name, "error", cerr)
+		if cerr := partitionClient.Close(closeCtx); cerr != nil {
+			slog.Warn("failed to close eventhub partition client", "partition", partitionID, "error", cerr)
 		}
 	}()
 
-	lastEnqueuedSequenceNumbers := make(map[string]SequenceNumbers)
+	receiveCtx, cancel := context.WithTimeout(ctx, receiveTimeout)
+	defer cancel()
 
-	for _, partitionID := range eventhubDetails.PartitionIDs {
-		partitionProps, err := consumerClient.GetPartitionProperties(ctx, partitionID, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get partition properties: %w", err)
-		}
+	events, err := partitionClient.ReceiveEvents(receiveCtx, 1, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to receive event: %w", err)
+	}
 
-		lastEnqueuedSequenceNumbers[partitionID] = SequenceNumbers{
-			Min: partitionProps.BeginningSequenceNumber,
-			Max: partitionProps.LastEnqueuedSequenceNumber,
-		}
+	if len(events) == 0 || events[0].EnqueuedTime == nil {
+		return time.Time{}, fmt.Errorf("no enqueued time for sequence number %d", sequenceNumber)
 	}
 
-	return lastEnqueuedSequenceNumbers, nil
+	if events[0].SequenceNumber != sequenceNumber {
+		return time.Time{}, fmt.Errorf("received sequence number %d instead of %d",
+			events[0].SequenceNumber, sequenceNumber)
+	}
+
+	return *events[0].EnqueuedTime, nil
 }
 
 func IsOwnershipExpired(ownership azeventhubs.Ownership, expirationDuration time.Duration) bool {
@@ -0,0 +1,99 @@
+package eventhub
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+)
+
+func TestGetSequenceNumbersLimitsConcurrency(t *testing.T) {
+	client := &fakePartitionClient{delay: 10 * time.Millisecond}
+	details := newTestDetails(10)
+
+	sequenceNumbers, err := getSequenceNumbers(context.Background(), client, details, 3)
+	if err != nil {
+		t.Fatalf("failed to get sequence numbers: %v", err)
+	}
+
+	if len(sequenceNumbers) != 10 || sequenceNumbers["7"].Max != 7 {
+		t.Fatalf("expected the sequence numbers of all partitions, got %v", sequenceNumbers)
+	}
+	if maxInFlight := client.maxInFlight.Load(); maxInFlight > 3 {
+		t.Fatalf("expected at most 3 requests in flight, got %d", maxInFlight)
+	}
+}
+
+func TestGetSequenceNumbersReturnsPartitionError(t *testing.T) {
+	// the other partitions only return once the failed partition cancelled them
+	client := &fakePartitionClient{failingPartition: "0", block: true}
+	details := newTestDetails(8)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := getSequenceNumbers(context.Background(), client, details, 2)
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		if err == nil || errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the error of the failed partition, got %v", err)
+		}
+		if requests := client.requests.Load(); requests > 2 {
+			t.Fatalf("expected no requests after the failed partition, got %d requests", requests)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a failed partition not to block the others")
+	}
+}
+
+func newTestDetails(partitionCount int) *Details {
+	details := &Details{Name: "eh", PartitionCount: partitionCount}
+	for i := range partitionCount {
+		details.PartitionIDs = append(details.PartitionIDs, strconv.Itoa(i))
+	}
+	return details
+}
+
+// fakePartitionClient returns the partition id as sequence number and tracks how many requests are in flight.
+type fakePartitionClient struct {
+	delay            time.Duration
+	block            bool
+	failingPartition string
+
+	requests    atomic.Int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (c *fakePartitionClient) GetPartitionProperties(ctx context.Context, partitionID string,
+	_ *azeventhubs.GetPartitionPropertiesOptions) (azeventhubs.PartitionProperties, error) {
+
+	c.requests.Add(1)
+	inFlight := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+
+	for {
+		maxInFlight := c.maxInFlight.Load()
+		if inFlight <= maxInFlight || c.maxInFlight.CompareAndSwap(maxInFlight, inFlight) {
+			break
+		}
+	}
+
+	if partitionID == c.failingPartition {
+		return azeventhubs.PartitionProperties{}, errors.New("unavailable")
+	}
+	if c.block {
+		<-ctx.Done()
+		return azeventhubs.PartitionProperties{}, ctx.Err()
+	}
+	time.Sleep(c.delay)
+
+	sequenceNumber, _ := strconv.ParseInt(partitionID, 10, 64)
+	return azeventhubs.PartitionProperties{LastEnqueuedSequenceNumber: sequenceNumber}, nil
+}
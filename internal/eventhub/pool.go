@@ -0,0 +1,101 @@
+package eventhub
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+)
+
+type consumerClientKey struct {
+	endpoint string
+	eventHub string
+}
+
+// ConsumerClientPool keeps one AMQP consumer client per eventhub open across collection cycles, so the connection
+// and its authentication don't have to be set up again in every cycle.
+type ConsumerClientPool struct {
+	credential    *azidentity.DefaultAzureCredential
+	consumerGroup string
+
+	mu      sync.Mutex
+	clients map[consumerClientKey]*azeventhubs.ConsumerClient
+}
+
+// NewConsumerClientPool returns a pool whose clients receive events with the given consumer group.
+func NewConsumerClientPool(credential *azidentity.DefaultAzureCredential, consumerGroup string) *ConsumerClientPool {
+	return &ConsumerClientPool{
+		credential:    credential,
+		consumerGroup: consumerGroup,
+		clients:       make(map[consumerClientKey]*azeventhubs.ConsumerClient),
+	}
+}
+
+// Get returns the consumer client of an eventhub and opens it if the pool doesn't contain one yet.
+func (p *ConsumerClientPool) Get(endpoint, eventHub string) (*azeventhubs.ConsumerClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := consumerClientKey{endpoint: endpoint, eventHub: eventHub}
+	if consumerClient, ok := p.clients[key]; ok {
+		return consumerClient, nil
+	}
+
+	consumerClient, err := NewConsumerClient(p.credential, endpoint, eventHub, p.consumerGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("opened pooled consumer client", "endpoint", endpoint, "eventhub", eventHub)
+	p.clients[key] = consumerClient
+	return consumerClient, nil
+}
+
+// Invalidate closes a consumer client which failed, so the next Get reconnects.
+func (p *ConsumerClientPool) Invalidate(endpoint, eventHub string, consumerClient *azeventhubs.ConsumerClient) {
+	p.mu.Lock()
+	key := consumerClientKey{endpoint: endpoint, eventHub: eventHub}
+	if p.clients[key] == consumerClient {
+		delete(p.clients, key)
+	}
+	p.mu.Unlock()
+
+	CloseConsumerClient(consumerClient, eventHub)
+}
+
+// Retain closes the consumer clients of all eventhubs of a namespace which aren't contained in eventHubs anymore.
+func (p *ConsumerClientPool) Retain(endpoint string, eventHubs []Details) {
+	existing := make(map[string]bool, len(eventHubs))
+	for _, eventHub := range eventHubs {
+		existing[eventHub.Name] = true
+	}
+
+	evicted := make(map[consumerClientKey]*azeventhubs.ConsumerClient)
+
+	p.mu.Lock()
+	for key, consumerClient := range p.clients {
+		if key.endpoint == endpoint && !existing[key.eventHub] {
+			evicted[key] = consumerClient
+			delete(p.clients, key)
+		}
+	}
+	p.mu.Unlock()
+
+	for key, consumerClient := range evicted {
+		slog.Info("closing consumer client of removed eventhub", "endpoint", endpoint, "eventhub", key.eventHub)
+		CloseConsumerClient(consumerClient, key.eventHub)
+	}
+}
+
+// Close closes all pooled consumer clients.
+func (p *ConsumerClientPool) Close() {
+	p.mu.Lock()
+	clients := p.clients
+	p.clients = make(map[consumerClientKey]*azeventhubs.ConsumerClient)
+	p.mu.Unlock()
+
+	for key, consumerClient := range clients {
+		CloseConsumerClient(consumerClient, key.eventHub)
+	}
+}
@@ -0,0 +1,64 @@
+package eventhub
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+)
+
+const (
+	testEndpoint      = "my-ns.servicebus.windows.net"
+	otherTestEndpoint = "other-ns.servicebus.windows.net"
+)
+
+func TestConsumerClientPoolInvalidate(t *testing.T) {
+	pool := NewConsumerClientPool(nil, "$Default")
+	defer pool.Close()
+
+	client := mustGet(t, pool, testEndpoint, "eh")
+	if mustGet(t, pool, testEndpoint, "eh") != client {
+		t.Fatal("expected the pooled client to be reused")
+	}
+
+	pool.Invalidate(testEndpoint, "eh", client)
+	reconnected := mustGet(t, pool, testEndpoint, "eh")
+	if reconnected == client {
+		t.Fatal("expected an invalidated client to be replaced")
+	}
+
+	// a client which already was replaced must not evict its replacement
+	pool.Invalidate(testEndpoint, "eh", client)
+	if mustGet(t, pool, testEndpoint, "eh") != reconnected {
+		t.Fatal("expected the replacement to stay pooled")
+	}
+}
+
+func TestConsumerClientPoolRetain(t *testing.T) {
+	pool := NewConsumerClientPool(nil, "$Default")
+	defer pool.Close()
+
+	retained := mustGet(t, pool, testEndpoint, "retained")
+	removed := mustGet(t, pool, testEndpoint, "removed")
+	otherNamespace := mustGet(t, pool, otherTestEndpoint, "removed")
+
+	pool.Retain(testEndpoint, []Details{{Name: "retained"}})
+
+	if mustGet(t, pool, testEndpoint, "retained") != retained {
+		t.Error("expected the client of an existing eventhub to be retained")
+	}
+	if mustGet(t, pool, testEndpoint, "removed") == removed {
+		t.Error("expected the client of a removed eventhub to be closed")
+	}
+	if mustGet(t, pool, otherTestEndpoint, "removed") != otherNamespace {
+		t.Error("expected the clients of other namespaces to be retained")
+	}
+}
+
+func mustGet(t *testing.T, pool *ConsumerClientPool, endpoint, eventHub string) *azeventhubs.ConsumerClient {
+	t.Helper()
+	consumerClient, err := pool.Get(endpoint, eventHub)
+	if err != nil {
+		t.Fatalf("failed to get consumer client: %v", err)
+	}
+	return consumerClient
+}
@@ -0,0 +1,204 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status describes the state of the collection cycles.
+type Status struct {
+	// LastSuccess is the time the last cycle without errors was published.
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// LastCompleted is the time the last cycle was published, with or without errors.
+	LastCompleted *time.Time `json:"lastCompleted,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	// Reason explains why the probe failed.
+	Reason     string                     `json:"reason,omitempty"`
+	Namespaces map[string]NamespaceStatus `json:"namespaces"`
+}
+
+// NamespaceStatus describes the result of the last collection of a namespace.
+type NamespaceStatus struct {
+	Healthy     bool       `json:"healthy"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+// Tracker observes the collection cycles. It reports a cycle which is stalled, i.e. still running although it
+// should have been cancelled by the cycle timeout a while ago, and whether cycles are completed regularly.
+type Tracker struct {
+	stallAfter time.Duration
+	liveAfter  time.Duration
+	now        func() time.Time
+
+	mu             sync.Mutex
+	startedAt      time.Time
+	running        bool
+	cycleStartedAt time.Time
+	lastSuccess    time.Time
+	lastCompleted  time.Time
+	lastError      string
+	namespaces     map[string]NamespaceStatus
+}
+
+// NewTracker returns a tracker which considers a cycle stalled once it runs longer than stallAfter, and the
+// application not alive once no cycle was completed for liveAfter. A duration of 0 disables the respective check.
+func NewTracker(stallAfter, liveAfter time.Duration) *Tracker {
+	now := time.Now
+	return &Tracker{
+		stallAfter: stallAfter,
+		liveAfter:  liveAfter,
+		now:        now,
+		startedAt:  now(),
+		namespaces: make(map[string]NamespaceStatus),
+	}
+}
+
+// CycleStarted marks the start of a collection cycle.
+func (t *Tracker) CycleStarted() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.running = true
+	t.cycleStartedAt = t.now()
+}
+
+// NamespaceCollected records the result of the collection of a namespace.
+func (t *Tracker) NamespaceCollected(endpoint string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := t.namespaces[endpoint]
+	status.Healthy = err == nil
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+	} else {
+		now := t.now()
+		status.LastSuccess = &now
+	}
+	t.namespaces[endpoint] = status
+}
+
+// CycleFinished marks the end of the running collection cycle, whose metrics were published. err is the error of
+// the failed targets, if any.
+func (t *Tracker) CycleFinished(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.running = false
+	t.lastCompleted = t.now()
+	t.lastError = ""
+	if err != nil {
+		t.lastError = err.Error()
+	} else {
+		t.lastSuccess = t.lastCompleted
+	}
+}
+
+// Check returns an error if the running collection cycle is stalled.
+func (t *Tracker) Check() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.checkStalled()
+}
+
+// Ready returns the status and whether a cycle was published yet.
+func (t *Tracker) Ready() (Status, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := t.status()
+	if t.lastCompleted.IsZero() {
+		status.Reason = "no collection cycle published yet"
+		return status, false
+	}
+	return status, true
+}
+
+// Live returns the status and whether the application is alive, i.e. no cycle is stalled and the last cycle was
+// completed recently.
+func (t *Tracker) Live() (Status, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := t.status()
+	if err := t.checkStalled(); err != nil {
+		status.Reason = err.Error()
+		return status, false
+	}
+
+	if t.liveAfter <= 0 {
+		return status, true
+	}
+
+	since := t.lastCompleted
+	if since.IsZero() {
+		since = t.startedAt
+	}
+	if elapsed := t.now().Sub(since); elapsed > t.liveAfter {
+		status.Reason = fmt.Sprintf("no collection cycle completed for %s", elapsed.Round(time.Second))
+		return status, false
+	}
+	return status, true
+}
+
+// ReadyHandler serves the readiness probe.
+func (t *Tracker) ReadyHandler() http.Handler {
+	return probeHandler(t.Ready)
+}
+
+// LiveHandler serves the liveness probe.
+func (t *Tracker) LiveHandler() http.Handler {
+	return probeHandler(t.Live)
+}
+
+func probeHandler(probe func() (Status, bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		status, ok := probe()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			slog.Warn("failed to write probe status", "error", err)
+		}
+	})
+}
+
+func (t *Tracker) checkStalled() error {
+	if !t.running || t.stallAfter <= 0 {
+		return nil
+	}
+
+	if running := t.now().Sub(t.cycleStartedAt); running > t.stallAfter {
+		return fmt.Errorf("collection cycle stalled: running for %s", running.Round(time.Second))
+	}
+	return nil
+}
+
+func (t *Tracker) status() Status {
+	status := Status{
+		LastSuccess:   timeOrNil(t.lastSuccess),
+		LastCompleted: timeOrNil(t.lastCompleted),
+		LastError:     t.lastError,
+		Namespaces:    make(map[string]NamespaceStatus, len(t.namespaces)),
+	}
+	for endpoint, namespaceStatus := range t.namespaces {
+		status.Namespaces[endpoint] = namespaceStatus
+	}
+	return status
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,116 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTrackerDetectsStalledCycle(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker := NewTracker(time.Minute, 0)
+	tracker.now = func() time.Time { return now }
+
+	if err := tracker.Check(); err != nil {
+		t.Fatalf("expected no error before the first cycle, got %v", err)
+	}
+
+	tracker.CycleStarted()
+	now = now.Add(30 * time.Second)
+	if err := tracker.Check(); err != nil {
+		t.Fatalf("expected no error while the cycle is within its deadline, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := tracker.Check(); err == nil {
+		t.Fatal("expected a stalled cycle to be reported")
+	}
+	if _, ok := tracker.Live(); ok {
+		t.Fatal("expected a stalled cycle to fail the liveness probe")
+	}
+
+	tracker.CycleFinished(nil)
+	if err := tracker.Check(); err != nil {
+		t.Fatalf("expected no error after the cycle finished, got %v", err)
+	}
+}
+
+func TestTrackerProbes(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker := NewTracker(0, 15*time.Minute)
+	tracker.now = func() time.Time { return now }
+	tracker.startedAt = now
+
+	if rec := probe(tracker.ReadyHandler()); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected ready to fail before the first cycle, got %d", rec.Code)
+	}
+
+	tracker.CycleStarted()
+	tracker.NamespaceCollected("ns-a.servicebus.windows.net", nil)
+	tracker.NamespaceCollected("ns-b.servicebus.windows.net", errors.New("throttled"))
+	tracker.CycleFinished(errors.New("throttled"))
+
+	rec := probe(tracker.ReadyHandler())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected ready to succeed after the first cycle, got %d", rec.Code)
+	}
+
+	var status Status
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if status.LastSuccess != nil || status.LastError != "throttled" {
+		t.Fatalf("expected the last cycle to be failed, got %+v", status)
+	}
+	if !status.Namespaces["ns-a.servicebus.windows.net"].Healthy ||
+		status.Namespaces["ns-b.servicebus.windows.net"].Healthy {
+		t.Fatalf("expected only ns-a to be healthy, got %+v", status.Namespaces)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if rec := probe(tracker.LiveHandler()); rec.Code != http.StatusOK {
+		t.Fatalf("expected live to succeed, got %d", rec.Code)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if rec := probe(tracker.LiveHandler()); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected live to fail without a completed cycle, got %d", rec.Code)
+	}
+}
+
+func TestTrackerToleratesSlowCycles(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// cycle timeout of 10m with a grace period of 1m and an interval of 5m, which allows 3 intervals
+	tracker := NewTracker(11*time.Minute, 3*5*time.Minute+11*time.Minute)
+	tracker.now = func() time.Time { return now }
+	tracker.startedAt = now
+
+	tracker.CycleStarted()
+	tracker.CycleFinished(nil)
+
+	// the next cycle starts an interval later and almost stalls before it completes
+	now = now.Add(5 * time.Minute)
+	tracker.CycleStarted()
+	now = now.Add(11 * time.Minute)
+	if _, ok := tracker.Live(); !ok {
+		t.Fatal("expected a slow cycle within its timeout to pass the liveness probe")
+	}
+	tracker.CycleFinished(errors.New("timeout"))
+
+	now = now.Add(26*time.Minute + time.Second)
+	if _, ok := tracker.Live(); ok {
+		t.Fatal("expected live to fail without a completed cycle")
+	}
+}
+
+func probe(handler http.Handler) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec
+}
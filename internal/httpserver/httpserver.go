@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,13 +13,19 @@ type Server struct {
 	mux         *http.ServeMux
 	address     string
 	readTimeout time.Duration
+	healthCheck atomic.Pointer[func() error]
 }
 
 func NewServer(address string, readTimeout time.Duration) *Server {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", healthHandler)
+	s := &Server{mux: http.NewServeMux(), address: address, readTimeout: readTimeout}
+	s.mux.HandleFunc("/health", s.healthHandler)
 
-	return &Server{mux: mux, address: address, readTimeout: readTimeout}
+	return s
+}
+
+// SetHealthCheck sets a check which makes the /health endpoint fail while it returns an error.
+func (s *Server) SetHealthCheck(check func() error) {
+	s.healthCheck.Store(&check)
 }
 
 // Handle mounts an additional handler onto the server.
@@ -40,6 +47,13 @@ func (s *Server) Run() {
 	}
 }
 
-func healthHandler(w http.ResponseWriter, _ *http.Request) {
+func (s *Server) healthHandler(w http.ResponseWriter, _ *http.Request) {
+	if check := s.healthCheck.Load(); check != nil {
+		if err := (*check)(); err != nil {
+			slog.Warn("health check failed", "error", err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
 	_, _ = fmt.Fprintf(w, "OK\n")
 }
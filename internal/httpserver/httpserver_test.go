@@ -1,6 +1,7 @@
 package httpserver
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -47,3 +48,25 @@ func TestHandleMountsAdditionalHandler(t *testing.T) {
 		t.Fatalf("expected /health status %d, got %d", http.StatusOK, rec.Code)
 	}
 }
+
+func TestHealthFailsWhileCheckFails(t *testing.T) {
+	s := NewServer(":0", time.Second)
+
+	var checkErr error
+	s.SetHealthCheck(func() error { return checkErr })
+
+	checkErr = errors.New("collection cycle stalled")
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	checkErr = nil
+	rec = httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
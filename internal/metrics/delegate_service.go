@@ -1,9 +1,22 @@
 package metrics
 
-import "log/slog"
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
 
 type delegateService struct {
 	delegates []RecordService
+	// pushDurations holds how long the previous push of each delegate took. They are recorded in the next cycle,
+	// since the metrics of a cycle are complete once they are pushed.
+	pushDurations []time.Duration
+	pushed        bool
+}
+
+func newDelegateService(delegates []RecordService) *delegateService {
+	return &delegateService{delegates: delegates, pushDurations: make([]time.Duration, len(delegates))}
 }
 
 func NewDelegateService(delegates ...RecordService) Service {
@@ -13,7 +26,7 @@ func NewDelegateService(delegates ...RecordService) Service {
 		delegates = append(delegates, newLogService())
 	}
 
-	return &service{recorder: &delegateService{delegates: delegates}}
+	return &service{recorder: newDelegateService(delegates), stats: newPipelineStats()}
 }
 
 func (s *delegateService) RecordMetric(metric *Metric, labels map[string]string, value float64) {
@@ -26,13 +39,44 @@ func (s *delegateService) StartCycle() {
 	for _, delegate := range s.delegates {
 		delegate.StartCycle()
 	}
+
+	if !s.pushed {
+		return
+	}
+	for i, delegate := range s.delegates {
+		s.RecordMetric(PushDuration, map[string]string{"exporter": exporterName(delegate)},
+			s.pushDurations[i].Seconds())
+	}
 }
 
+// PushMetrics pushes the metrics to every delegate, even if a previous one failed.
 func (s *delegateService) PushMetrics() error {
-	for _, delegate := range s.delegates {
-		if err := delegate.PushMetrics(); err != nil {
-			return err
+	s.pushed = true
+	var errs []error
+	for i, delegate := range s.delegates {
+		start := time.Now()
+		err := delegate.PushMetrics()
+		s.pushDurations[i] = time.Since(start)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", exporterName(delegate), err))
 		}
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+func exporterName(delegate RecordService) string {
+	switch delegate.(type) {
+	case *appInsightsService:
+		return "appinsights"
+	case *prometheusService:
+		return "prometheus"
+	case *pushGatewayService:
+		return "pushgateway"
+	case *OtlpService:
+		return "otlp"
+	case *logService:
+		return "log"
+	default:
+		return fmt.Sprintf("%T", delegate)
+	}
 }
@@ -8,6 +8,25 @@ const (
 	labelPartitionID   = "partition_id"
 	labelConsumerGroup = "consumer_group"
 	labelOwner         = "owner"
+	labelProtocol      = "protocol"
+	labelStorage       = "storage_account"
+)
+
+// stages of the collection pipeline whose errors are counted.
+const (
+	StageToken      = "token"
+	StageManagement = "management"
+	StageAMQP       = "amqp"
+	StageBlobList   = "blob_list"
+	StageTableList  = "table_list"
+	StageCheckpoint = "checkpoint"
+	StagePush       = "push"
+)
+
+// protocols by which consumer groups consume an eventhub.
+const (
+	ProtocolEventHubs = "eventhubs"
+	ProtocolKafka     = "kafka"
 )
 
 type Metric struct {
@@ -55,7 +74,7 @@ var EventhubSequenceNumberMaxSum = &Metric{
 var ConsumerGroupInfo = &Metric{
 	Name:   "consumer_group_info",
 	Help:   "consumer group info gauges. It will report 1 if the group is in the stable state, otherwise 0.",
-	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, "state"},
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelProtocol, "state"},
 }
 
 var ConsumerGroupOwners = &Metric{
@@ -80,16 +99,216 @@ var ConsumerGroupPartitionLag = &Metric{
 	Name: "consumer_group_partition_lag",
 	Help: "the number of messages a consumer group is lagging behind the last enqueued sequence number" +
 		" of a partition",
-	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID},
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID, labelProtocol},
 }
 
 var ConsumerGroupLag = &Metric{
 	Name:   "consumer_group_lag",
 	Help:   "the number of messages a consumer group is lagging behind across all partitions in an eventhub",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelProtocol},
+}
+
+var ConsumerGroupPartitionLagSeconds = &Metric{
+	Name: "consumer_group_partition_lag_seconds",
+	Help: "the time in seconds between the last enqueued event of a partition and the event checkpointed by a" +
+		" consumer group",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID},
+}
+
+var ConsumerGroupLagSeconds = &Metric{
+	Name:   "consumer_group_lag_seconds",
+	Help:   "the maximum time in seconds a consumer group is lagging behind across all partitions in an eventhub",
 	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup},
 }
 
+var EventhubPartitionIngressRate = &Metric{
+	Name:   "eventhub_partition_ingress_rate",
+	Help:   "the number of events per second enqueued to a partition since the previous collection cycle",
+	Labels: []string{labelNamespace, labelEventhub, labelPartitionID},
+}
+
+var EventhubIngressRate = &Metric{
+	Name:   "eventhub_ingress_rate",
+	Help:   "the number of events per second enqueued to an eventhub since the previous collection cycle",
+	Labels: []string{labelNamespace, labelEventhub},
+}
+
+var ConsumerGroupPartitionConsumeRate = &Metric{
+	Name:   "consumer_group_partition_consume_rate",
+	Help:   "the number of events per second a consumer group checkpointed on a partition since the previous cycle",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID},
+}
+
+var ConsumerGroupConsumeRate = &Metric{
+	Name:   "consumer_group_consume_rate",
+	Help:   "the number of events per second a consumer group checkpointed across all partitions in an eventhub",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup},
+}
+
+var ConsumerGroupPartitionLagGrowthRate = &Metric{
+	Name: "consumer_group_partition_lag_growth_rate",
+	Help: "the number of events per second the lag of a consumer group on a partition grows." +
+		" Negative values mean the consumer group is catching up.",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID},
+}
+
+var ConsumerGroupLagGrowthRate = &Metric{
+	Name: "consumer_group_lag_growth_rate",
+	Help: "the number of events per second the lag of a consumer group grows across all partitions in an eventhub." +
+		" Negative values mean the consumer group is catching up.",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup},
+}
+
+var ConsumerGroupCatchUpSeconds = &Metric{
+	Name: "consumer_group_catch_up_seconds",
+	Help: "the estimated time in seconds until a consumer group has drained its lag at the current rates." +
+		" It will report -1 if the lag isn't shrinking.",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup},
+}
+
+var ConsumerGroupPartitionRetentionHeadroom = &Metric{
+	Name: "consumer_group_partition_retention_headroom",
+	Help: "the number of events between the beginning of a partition and the next event a consumer group will" +
+		" process. The closer to 0, the closer the consumer group is to losing unprocessed events.",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID},
+}
+
+var ConsumerGroupPartitionDataLoss = &Metric{
+	Name: "consumer_group_partition_data_loss",
+	Help: "reports 1 if the checkpoint of a consumer group fell behind the beginning of a partition" +
+		" and unprocessed events were lost, otherwise 0.",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID},
+}
+
+var ConsumerGroupPartitionCheckpointStalledSeconds = &Metric{
+	Name:   "consumer_group_partition_checkpoint_stalled_seconds",
+	Help:   "the time in seconds since the checkpoint of a consumer group last advanced on a partition",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID},
+}
+
+var ConsumerGroupPartitionCheckpointAgeSeconds = &Metric{
+	Name:   "consumer_group_partition_checkpoint_age_seconds",
+	Help:   "the time in seconds since a consumer group last wrote the checkpoint of a partition",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID},
+}
+
+var ConsumerGroupDistinctOwners = &Metric{
+	Name:   "consumer_group_distinct_owners",
+	Help:   "the number of distinct owner instances holding an active ownership in a consumer group",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup},
+}
+
+var ConsumerGroupOwnerPartitionsMax = &Metric{
+	Name:   "consumer_group_owner_partitions_max",
+	Help:   "the number of partitions owned by the busiest owner instance of a consumer group",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup},
+}
+
+var ConsumerGroupOwnerPartitionsMin = &Metric{
+	Name:   "consumer_group_owner_partitions_min",
+	Help:   "the number of partitions owned by the least busy owner instance of a consumer group",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup},
+}
+
+var ConsumerGroupOwnerImbalanceRatio = &Metric{
+	Name: "consumer_group_owner_imbalance_ratio",
+	Help: "the number of partitions owned by the busiest owner instance divided by the average number of" +
+		" partitions per owner instance. It will report 1 if the partitions are evenly distributed.",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup},
+}
+
+var ConsumerGroupPartitionOwnershipChanges = &Metric{
+	Name: "consumer_group_partition_ownership_changes_total",
+	Help: "the number of times a partition of a consumer group changed hands since the application started. it " +
+		"resets to 0 when the application restarts",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID},
+}
+
+var ConsumerGroupPartitionOwnershipLastChange = &Metric{
+	Name:   "consumer_group_partition_ownership_last_change_timestamp_seconds",
+	Help:   "the unix timestamp at which a partition of a consumer group was last observed changing hands",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelPartitionID},
+}
+
+var BlobClientPoolClients = &Metric{
+	Name:   "blob_client_pool_clients",
+	Help:   "the number of blob clients and checkpoint stores which are kept for storage accounts and containers",
+	Labels: []string{},
+}
+
+var BlobClientPoolRequests = &Metric{
+	Name: "blob_client_pool_requests",
+	Help: "the number of times a blob client or checkpoint store was requested since the application started, " +
+		"by whether it was created or reused",
+	Labels: []string{"result"},
+}
+
+var ManagementRequestRetries = &Metric{
+	Name:   "management_request_retries",
+	Help:   "the number of retried requests to a management endpoint since the application started",
+	Labels: []string{"endpoint"},
+}
+
+var ManagementRequestThrottles = &Metric{
+	Name:   "management_request_throttles",
+	Help:   "the number of throttled responses of a management endpoint since the application started",
+	Labels: []string{"endpoint"},
+}
+
+var CollectionErrors = &Metric{
+	Name: "collection_errors",
+	Help: "reports 1 for every namespace, eventhub, consumer group or storage account whose metrics or checkpoints " +
+		"couldn't be collected in the last cycle, by the class of the error",
+	Labels: []string{labelNamespace, labelEventhub, labelConsumerGroup, labelStorage, "error_class"},
+}
+
+var CollectionCyclePartial = &Metric{
+	Name: "collection_cycle_partial",
+	Help: "reports 1 if the metrics of the last cycle are incomplete, because the cycle exceeded its timeout or " +
+		"targets failed, otherwise 0",
+	Labels: []string{},
+}
+
+var CollectionCycleDuration = &Metric{
+	Name:   "collection_cycle_duration_seconds",
+	Help:   "the time in seconds the last collection cycle took, without pushing the metrics",
+	Labels: []string{},
+}
+
+var CollectionLastSuccess = &Metric{
+	Name:   "collection_last_success_timestamp_seconds",
+	Help:   "the unix timestamp at which the last collection cycle without errors finished",
+	Labels: []string{},
+}
+
+var CollectionTargets = &Metric{
+	Name:   "collection_targets",
+	Help:   "the number of namespaces, eventhubs and consumer groups processed in the last collection cycle",
+	Labels: []string{"target"},
+}
+
+var CollectionStageErrors = &Metric{
+	Name:   "collection_stage_errors",
+	Help:   "the number of errors since the application started, by the stage of the collection pipeline",
+	Labels: []string{"stage"},
+}
+
+var PushDuration = &Metric{
+	Name:   "push_duration_seconds",
+	Help:   "the time in seconds the push of the previous collection cycle took, by exporter",
+	Labels: []string{"exporter"},
+}
+
 var allMetrics = []*Metric{NamespaceInfo, EventhubInfo, EventhubPartitionSequenceNumberMin,
 	EventhubSequenceNumberMinSum, EventhubPartitionSequenceNumberMax, EventhubSequenceNumberMaxSum, ConsumerGroupInfo,
 	ConsumerGroupOwners, ConsumerGroupEventsSum, ConsumerGroupPartitionOwner, ConsumerGroupPartitionLag,
-	ConsumerGroupLag}
+	ConsumerGroupLag, ConsumerGroupPartitionLagSeconds, ConsumerGroupLagSeconds, EventhubPartitionIngressRate,
+	EventhubIngressRate, ConsumerGroupPartitionConsumeRate, ConsumerGroupConsumeRate,
+	ConsumerGroupPartitionLagGrowthRate, ConsumerGroupLagGrowthRate, ConsumerGroupCatchUpSeconds,
+	ConsumerGroupPartitionRetentionHeadroom, ConsumerGroupPartitionDataLoss,
+	ConsumerGroupPartitionCheckpointStalledSeconds, ConsumerGroupPartitionCheckpointAgeSeconds,
+	ConsumerGroupDistinctOwners, ConsumerGroupOwnerPartitionsMax, ConsumerGroupOwnerPartitionsMin,
+	ConsumerGroupOwnerImbalanceRatio, ConsumerGroupPartitionOwnershipChanges, ConsumerGroupPartitionOwnershipLastChange,
+	BlobClientPoolClients, BlobClientPoolRequests, ManagementRequestRetries, ManagementRequestThrottles,
+	CollectionErrors, CollectionCyclePartial, CollectionCycleDuration, CollectionLastSuccess, CollectionTargets,
+	CollectionStageErrors, PushDuration}
@@ -1,9 +1,12 @@
 package metrics
 
 import (
+	"errors"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel/sdk/instrumentation"
@@ -30,6 +33,7 @@ func TestOtlpRecordMetricConcurrent(t *testing.T) {
 					labelNamespace:     "ns",
 					labelEventhub:      "eh",
 					labelConsumerGroup: "cg",
+					labelProtocol:      ProtocolEventHubs,
 				}, 1.0)
 			}
 		}()
@@ -167,6 +171,95 @@ func TestNewOtlpServiceUnsupportedProtocol(t *testing.T) {
 	}
 }
 
+func TestPipelineMetricsAvailableOnEveryExporter(t *testing.T) {
+	recorders := []*fakeRecordService{{}, {}}
+	s := NewDelegateService(recorders[0], recorders[1])
+
+	s.StartCollectionCycle()
+	s.RecordNamespaceInfo("ns", "ns.servicebus.windows.net")
+	s.RecordEventhubInfo("ns", "eh", 4, 1)
+	s.RecordConsumerGroupInfo("ns", "eh", "cg-1", ProtocolEventHubs, "stable")
+	s.RecordConsumerGroupInfo("ns", "eh", "cg-2", ProtocolKafka, "empty")
+	s.RecordStageError(StageAMQP)
+	s.RecordCollectionCycle(2*time.Second, false)
+	if err := s.PushMetrics(); err != nil {
+		t.Fatalf("failed to push metrics: %v", err)
+	}
+	s.StartCollectionCycle()
+
+	for i, recorder := range recorders {
+		expected := map[string]float64{
+			"collection_cycle_duration_seconds{}":       2,
+			"collection_targets{target=consumer_group}": 2,
+			"collection_targets{target=eventhub}":       1,
+			"collection_stage_errors{stage=amqp}":       1,
+			"collection_stage_errors{stage=push}":       0,
+		}
+		for key, value := range expected {
+			if got, ok := recorder.recorded[key]; !ok || got != value {
+				t.Errorf("expected %s=%v on exporter %d, got %v", key, value, i, got)
+			}
+		}
+		if _, ok := recorder.recorded["collection_last_success_timestamp_seconds{}"]; !ok {
+			t.Errorf("expected last success timestamp on exporter %d", i)
+		}
+		if _, ok := recorder.recorded["push_duration_seconds{exporter=*metrics.fakeRecordService}"]; !ok {
+			t.Errorf("expected push durations on exporter %d, got %v", i, recorder.recorded)
+		}
+	}
+}
+
+func TestFailedPushIsReportedWithNextCycle(t *testing.T) {
+	recorders := []*fakeRecordService{{pushErr: errors.New("unavailable")}, {}}
+	s := NewDelegateService(recorders[0], recorders[1])
+
+	s.StartCollectionCycle()
+	s.RecordCollectionCycle(time.Second, false)
+	if err := s.PushMetrics(); err == nil {
+		t.Fatal("expected the failed push to be returned")
+	}
+	if recorders[1].pushes != 1 {
+		t.Fatal("expected the metrics to be pushed to the other exporter")
+	}
+
+	s.StartCollectionCycle()
+	s.RecordCollectionCycle(time.Second, false)
+
+	for i, recorder := range recorders {
+		if got := recorder.recorded["collection_stage_errors{stage=push}"]; got != 1 {
+			t.Errorf("expected 1 push error on exporter %d, got %v", i, got)
+		}
+	}
+}
+
+// fakeRecordService keeps every value recorded since the test started.
+type fakeRecordService struct {
+	recorded map[string]float64
+	pushErr  error
+	pushes   int
+}
+
+func (s *fakeRecordService) RecordMetric(metric *Metric, labels map[string]string, value float64) {
+	if s.recorded == nil {
+		s.recorded = make(map[string]float64)
+	}
+
+	pairs := make([]string, 0, len(labels))
+	for key, labelValue := range labels {
+		pairs = append(pairs, key+"="+labelValue)
+	}
+	sort.Strings(pairs)
+	s.recorded[metric.Name+"{"+strings.Join(pairs, ",")+"}"] = value
+}
+
+func (s *fakeRecordService) StartCycle() {
+}
+
+func (s *fakeRecordService) PushMetrics() error {
+	s.pushes++
+	return s.pushErr
+}
+
 func newTestPrometheusService(t *testing.T) *prometheusService {
 	t.Helper()
 	s, ok := NewPrometheusService().(*prometheusService)
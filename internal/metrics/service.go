@@ -2,6 +2,8 @@ package metrics
 
 import (
 	"fmt"
+	"sync"
+	"time"
 )
 
 type Service interface {
@@ -9,12 +11,37 @@ type Service interface {
 	RecordEventhubInfo(namespace, eventhub string, partitionCount, messageRetentionInDays int)
 	RecordEventhubPartitionSequenceNumber(namespace, eventhub, partitionID string, seqMin, seqMax int64)
 	RecordEventhubSequenceNumberSum(namespace, eventhub string, seqMin, seqMax int64)
-	RecordConsumerGroupInfo(namespace, eventhub, consumerGroup string, state string)
+	RecordConsumerGroupInfo(namespace, eventhub, consumerGroup, protocol, state string)
 	RecordConsumerGroupOwners(namespace, eventhub, consumerGroup string, ownerCount int)
 	RecordConsumerGroupEvents(namespace, eventhub, consumerGroup string, eventCount int64)
 	RecordConsumerGroupPartitionOwner(namespace, eventhub, consumerGroup, partitionID, owner string, expired bool)
-	RecordConsumerGroupPartitionLag(namespace, eventhub, consumerGroup, partitionID string, lag int64)
-	RecordConsumerGroupLag(namespace, eventhub, consumerGroup string, lag int64)
+	RecordConsumerGroupPartitionLag(namespace, eventhub, consumerGroup, partitionID, protocol string, lag int64)
+	RecordConsumerGroupLag(namespace, eventhub, consumerGroup, protocol string, lag int64)
+	RecordConsumerGroupPartitionLagSeconds(namespace, eventhub, consumerGroup, partitionID string, lagSeconds float64)
+	RecordConsumerGroupLagSeconds(namespace, eventhub, consumerGroup string, lagSeconds float64)
+	RecordEventhubPartitionIngressRate(namespace, eventhub, partitionID string, rate float64)
+	RecordEventhubIngressRate(namespace, eventhub string, rate float64)
+	RecordConsumerGroupPartitionConsumeRate(namespace, eventhub, consumerGroup, partitionID string, rate float64)
+	RecordConsumerGroupConsumeRate(namespace, eventhub, consumerGroup string, rate float64)
+	RecordConsumerGroupPartitionLagGrowthRate(namespace, eventhub, consumerGroup, partitionID string, rate float64)
+	RecordConsumerGroupLagGrowthRate(namespace, eventhub, consumerGroup string, rate float64)
+	RecordConsumerGroupCatchUpSeconds(namespace, eventhub, consumerGroup string, catchUpSeconds float64)
+	RecordConsumerGroupPartitionRetention(namespace, eventhub, consumerGroup, partitionID string, headroom int64,
+		dataLoss bool)
+	RecordConsumerGroupPartitionCheckpointStalled(namespace, eventhub, consumerGroup, partitionID string,
+		stalledSeconds float64)
+	RecordConsumerGroupPartitionCheckpointAge(namespace, eventhub, consumerGroup, partitionID string,
+		ageSeconds float64)
+	RecordConsumerGroupOwnerDistribution(namespace, eventhub, consumerGroup string, distinctOwners, maxPartitions,
+		minPartitions int, imbalanceRatio float64)
+	RecordConsumerGroupPartitionOwnershipChanges(namespace, eventhub, consumerGroup, partitionID string, changes int64,
+		lastChange time.Time)
+	RecordBlobClientPool(clients int, created, reused int64)
+	RecordManagementRequests(endpoint string, retries, throttles int64)
+	RecordCollectionError(namespace, eventhub, consumerGroup, errorClass string)
+	RecordStorageAccountError(storageAccount, errorClass string)
+	RecordCollectionCycle(duration time.Duration, partial bool)
+	RecordStageError(stage string)
 	StartCollectionCycle()
 	PushMetrics() error
 }
@@ -27,9 +54,31 @@ type RecordService interface {
 
 type service struct {
 	recorder RecordService
+	stats    *pipelineStats
 }
 
+// pipelineStats counts the targets processed in the running cycle and the errors of the collection pipeline.
+type pipelineStats struct {
+	mu             sync.Mutex
+	namespaces     int
+	eventhubs      int
+	consumerGroups int
+	stageErrors    map[string]int64
+	lastSuccess    time.Time
+}
+
+func newPipelineStats() *pipelineStats {
+	return &pipelineStats{stageErrors: make(map[string]int64)}
+}
+
+var stages = []string{StageToken, StageManagement, StageAMQP, StageBlobList, StageTableList, StageCheckpoint,
+	StagePush}
+
 func (s *service) RecordNamespaceInfo(namespace, endpoint string) {
+	s.stats.mu.Lock()
+	s.stats.namespaces++
+	s.stats.mu.Unlock()
+
 	s.recorder.RecordMetric(NamespaceInfo, map[string]string{
 		labelNamespace: namespace,
 		"eh_endpoint":  endpoint},
@@ -37,6 +86,10 @@ func (s *service) RecordNamespaceInfo(namespace, endpoint string) {
 }
 
 func (s *service) RecordEventhubInfo(namespace, eventhub string, partitionCount int, messageRetentionInDays int) {
+	s.stats.mu.Lock()
+	s.stats.eventhubs++
+	s.stats.mu.Unlock()
+
 	s.recorder.RecordMetric(EventhubInfo, map[string]string{
 		labelNamespace:      namespace,
 		labelEventhub:       eventhub,
@@ -71,7 +124,10 @@ func (s *service) RecordEventhubSequenceNumberSum(namespace, eventhub string, se
 		float64(seqMax))
 }
 
-func (s *service) RecordConsumerGroupInfo(namespace, eventhub string, consumerGroup string, state string) {
+func (s *service) RecordConsumerGroupInfo(namespace, eventhub, consumerGroup, protocol, state string) {
+	s.stats.mu.Lock()
+	s.stats.consumerGroups++
+	s.stats.mu.Unlock()
 
 	value := 1.0
 	if state != "stable" {
@@ -82,6 +138,7 @@ func (s *service) RecordConsumerGroupInfo(namespace, eventhub string, consumerGr
 		labelNamespace:     namespace,
 		labelEventhub:      eventhub,
 		labelConsumerGroup: consumerGroup,
+		labelProtocol:      protocol,
 		"state":            state},
 		value)
 }
@@ -119,27 +176,254 @@ func (s *service) RecordConsumerGroupPartitionOwner(namespace, eventhub, consume
 		value)
 }
 
-func (s *service) RecordConsumerGroupPartitionLag(namespace, eventhub, consumerGroup, partitionID string, lag int64) {
+func (s *service) RecordConsumerGroupPartitionLag(namespace, eventhub, consumerGroup, partitionID, protocol string,
+	lag int64) {
 	s.recorder.RecordMetric(ConsumerGroupPartitionLag, map[string]string{
 		labelNamespace:     namespace,
 		labelEventhub:      eventhub,
 		labelConsumerGroup: consumerGroup,
-		labelPartitionID:   partitionID},
+		labelPartitionID:   partitionID,
+		labelProtocol:      protocol},
 		float64(lag))
 }
 
-func (s *service) RecordConsumerGroupLag(namespace, eventhub, consumerGroup string, lag int64) {
+func (s *service) RecordConsumerGroupLag(namespace, eventhub, consumerGroup, protocol string, lag int64) {
 	s.recorder.RecordMetric(ConsumerGroupLag, map[string]string{
 		labelNamespace:     namespace,
 		labelEventhub:      eventhub,
-		labelConsumerGroup: consumerGroup},
+		labelConsumerGroup: consumerGroup,
+		labelProtocol:      protocol},
 		float64(lag))
 }
 
+func (s *service) RecordConsumerGroupPartitionLagSeconds(namespace, eventhub, consumerGroup, partitionID string,
+	lagSeconds float64) {
+	s.recorder.RecordMetric(ConsumerGroupPartitionLagSeconds, map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup,
+		labelPartitionID:   partitionID},
+		lagSeconds)
+}
+
+func (s *service) RecordConsumerGroupLagSeconds(namespace, eventhub, consumerGroup string, lagSeconds float64) {
+	s.recorder.RecordMetric(ConsumerGroupLagSeconds, map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup},
+		lagSeconds)
+}
+
+func (s *service) RecordEventhubPartitionIngressRate(namespace, eventhub, partitionID string, rate float64) {
+	s.recorder.RecordMetric(EventhubPartitionIngressRate, map[string]string{
+		labelNamespace:   namespace,
+		labelEventhub:    eventhub,
+		labelPartitionID: partitionID},
+		rate)
+}
+
+func (s *service) RecordEventhubIngressRate(namespace, eventhub string, rate float64) {
+	s.recorder.RecordMetric(EventhubIngressRate, map[string]string{
+		labelNamespace: namespace,
+		labelEventhub:  eventhub},
+		rate)
+}
+
+func (s *service) RecordConsumerGroupPartitionConsumeRate(namespace, eventhub, consumerGroup, partitionID string,
+	rate float64) {
+	s.recorder.RecordMetric(ConsumerGroupPartitionConsumeRate, map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup,
+		labelPartitionID:   partitionID},
+		rate)
+}
+
+func (s *service) RecordConsumerGroupConsumeRate(namespace, eventhub, consumerGroup string, rate float64) {
+	s.recorder.RecordMetric(ConsumerGroupConsumeRate, map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup},
+		rate)
+}
+
+func (s *service) RecordConsumerGroupPartitionLagGrowthRate(namespace, eventhub, consumerGroup, partitionID string,
+	rate float64) {
+	s.recorder.RecordMetric(ConsumerGroupPartitionLagGrowthRate, map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup,
+		labelPartitionID:   partitionID},
+		rate)
+}
+
+func (s *service) RecordConsumerGroupLagGrowthRate(namespace, eventhub, consumerGroup string, rate float64) {
+	s.recorder.RecordMetric(ConsumerGroupLagGrowthRate, map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup},
+		rate)
+}
+
+func (s *service) RecordConsumerGroupCatchUpSeconds(namespace, eventhub, consumerGroup string,
+	catchUpSeconds float64) {
+	s.recorder.RecordMetric(ConsumerGroupCatchUpSeconds, map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup},
+		catchUpSeconds)
+}
+
+func (s *service) RecordConsumerGroupPartitionRetention(namespace, eventhub, consumerGroup, partitionID string,
+	headroom int64, dataLoss bool) {
+
+	labels := map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup,
+		labelPartitionID:   partitionID}
+
+	s.recorder.RecordMetric(ConsumerGroupPartitionRetentionHeadroom, labels, float64(headroom))
+
+	value := 0.0
+	if dataLoss {
+		value = 1.0
+	}
+
+	s.recorder.RecordMetric(ConsumerGroupPartitionDataLoss, labels, value)
+}
+
+func (s *service) RecordConsumerGroupPartitionCheckpointStalled(namespace, eventhub, consumerGroup,
+	partitionID string, stalledSeconds float64) {
+	s.recorder.RecordMetric(ConsumerGroupPartitionCheckpointStalledSeconds, map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup,
+		labelPartitionID:   partitionID},
+		stalledSeconds)
+}
+
+func (s *service) RecordConsumerGroupPartitionCheckpointAge(namespace, eventhub, consumerGroup,
+	partitionID string, ageSeconds float64) {
+	s.recorder.RecordMetric(ConsumerGroupPartitionCheckpointAgeSeconds, map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup,
+		labelPartitionID:   partitionID},
+		ageSeconds)
+}
+
+func (s *service) RecordConsumerGroupOwnerDistribution(namespace, eventhub, consumerGroup string, distinctOwners,
+	maxPartitions, minPartitions int, imbalanceRatio float64) {
+
+	labels := map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup}
+
+	s.recorder.RecordMetric(ConsumerGroupDistinctOwners, labels, float64(distinctOwners))
+	s.recorder.RecordMetric(ConsumerGroupOwnerPartitionsMax, labels, float64(maxPartitions))
+	s.recorder.RecordMetric(ConsumerGroupOwnerPartitionsMin, labels, float64(minPartitions))
+	s.recorder.RecordMetric(ConsumerGroupOwnerImbalanceRatio, labels, imbalanceRatio)
+}
+
+func (s *service) RecordConsumerGroupPartitionOwnershipChanges(namespace, eventhub, consumerGroup,
+	partitionID string, changes int64, lastChange time.Time) {
+
+	labels := map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup,
+		labelPartitionID:   partitionID}
+
+	s.recorder.RecordMetric(ConsumerGroupPartitionOwnershipChanges, labels, float64(changes))
+
+	if !lastChange.IsZero() {
+		s.recorder.RecordMetric(ConsumerGroupPartitionOwnershipLastChange, labels, float64(lastChange.Unix()))
+	}
+}
+
+func (s *service) RecordBlobClientPool(clients int, created, reused int64) {
+	s.recorder.RecordMetric(BlobClientPoolClients, map[string]string{}, float64(clients))
+	s.recorder.RecordMetric(BlobClientPoolRequests, map[string]string{"result": "created"}, float64(created))
+	s.recorder.RecordMetric(BlobClientPoolRequests, map[string]string{"result": "reused"}, float64(reused))
+}
+
+func (s *service) RecordManagementRequests(endpoint string, retries, throttles int64) {
+	labels := map[string]string{"endpoint": endpoint}
+	s.recorder.RecordMetric(ManagementRequestRetries, labels, float64(retries))
+	s.recorder.RecordMetric(ManagementRequestThrottles, labels, float64(throttles))
+}
+
+func (s *service) RecordCollectionError(namespace, eventhub, consumerGroup, errorClass string) {
+	s.recorder.RecordMetric(CollectionErrors, map[string]string{
+		labelNamespace:     namespace,
+		labelEventhub:      eventhub,
+		labelConsumerGroup: consumerGroup,
+		labelStorage:       "",
+		"error_class":      errorClass},
+		1.0)
+}
+
+func (s *service) RecordStorageAccountError(storageAccount, errorClass string) {
+	s.recorder.RecordMetric(CollectionErrors, map[string]string{
+		labelNamespace:     "",
+		labelEventhub:      "",
+		labelConsumerGroup: "",
+		labelStorage:       storageAccount,
+		"error_class":      errorClass},
+		1.0)
+}
+
+func (s *service) RecordCollectionCycle(duration time.Duration, partial bool) {
+	value := 0.0
+	if partial {
+		value = 1.0
+	}
+	s.recorder.RecordMetric(CollectionCyclePartial, map[string]string{}, value)
+	s.recorder.RecordMetric(CollectionCycleDuration, map[string]string{}, duration.Seconds())
+
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	if !partial {
+		s.stats.lastSuccess = time.Now()
+	}
+	if !s.stats.lastSuccess.IsZero() {
+		s.recorder.RecordMetric(CollectionLastSuccess, map[string]string{}, float64(s.stats.lastSuccess.Unix()))
+	}
+
+	s.recorder.RecordMetric(CollectionTargets, map[string]string{"target": "namespace"},
+		float64(s.stats.namespaces))
+	s.recorder.RecordMetric(CollectionTargets, map[string]string{"target": "eventhub"}, float64(s.stats.eventhubs))
+	s.recorder.RecordMetric(CollectionTargets, map[string]string{"target": "consumer_group"},
+		float64(s.stats.consumerGroups))
+
+	for _, stage := range stages {
+		s.recorder.RecordMetric(CollectionStageErrors, map[string]string{"stage": stage},
+			float64(s.stats.stageErrors[stage]))
+	}
+}
+
+func (s *service) RecordStageError(stage string) {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	s.stats.stageErrors[stage]++
+}
+
 func (s *service) StartCollectionCycle() {
+	s.stats.mu.Lock()
+	s.stats.namespaces, s.stats.eventhubs, s.stats.consumerGroups = 0, 0, 0
+	s.stats.mu.Unlock()
+
 	s.recorder.StartCycle()
 }
 
 func (s *service) PushMetrics() error {
-	return s.recorder.PushMetrics()
+	if err := s.recorder.PushMetrics(); err != nil {
+		s.RecordStageError(StagePush)
+		return err
+	}
+	return nil
 }
@@ -1,5 +1,26 @@
 package rest
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var ErrAuthentication = errors.New("authentication error")
+
+// ErrToken marks requests which failed since no token could be acquired.
+var ErrToken = errors.New("failed to get token")
+
+// errParse marks responses which could not be parsed. They are not retried.
+var errParse = errors.New("failed to parse response")
+
+// StatusError is returned for requests which failed with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the delay the server asked for in the Retry-After header.
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status=%d", e.StatusCode)
+}
@@ -2,15 +2,21 @@ package rest
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"path"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/deviceinsight/eventhub-metrics/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ResponseParser[T any] func(response *http.Response) (*T, error)
@@ -26,7 +32,7 @@ func GetToken(ctx context.Context, credential *azidentity.DefaultAzureCredential
 	options := policy.TokenRequestOptions{Scopes: []string{scope.String()}}
 	token, err := credential.GetToken(ctx, options)
 	if err != nil {
-		return "", fmt.Errorf("failed to get token: %w", err)
+		return "", fmt.Errorf("%w: %w", ErrToken, err)
 	}
 	return token.Token, nil
 }
@@ -42,9 +48,76 @@ func GetURL(endpoint string, paths ...string) (*url.URL, error) {
 	return u, nil
 }
 
+// PerformRequest performs a GET request and parses its response. Requests which fail with a network error or a
+// retryable status are retried with a jittered exponential backoff, honouring the Retry-After header.
 func PerformRequest[T any](ctx context.Context, token string, url *url.URL,
 	responseParser ResponseParser[T]) (*T, error) {
 
+	ctx, span := tracing.Start(ctx, "rest.PerformRequest", semconv.URLFull(url.String()))
+	parsed, err := performRequestWithRetries(ctx, token, url, responseParser)
+	tracing.End(span, err)
+	return parsed, err
+}
+
+func performRequestWithRetries[T any](ctx context.Context, token string, url *url.URL,
+	responseParser ResponseParser[T]) (*T, error) {
+
+	retryOptions := getOptions()
+
+	for retry := 0; ; retry++ {
+		parsed, err := performAttempt(ctx, token, url, responseParser, retryOptions.Timeout)
+		if err == nil {
+			return parsed, nil
+		}
+
+		var statusErr *StatusError
+		isStatusErr := errors.As(err, &statusErr)
+
+		throttled := isStatusErr && isThrottled(statusErr.StatusCode)
+		if throttled {
+			recordThrottle(url.Host)
+		}
+
+		if retry >= retryOptions.MaxRetries || ctx.Err() != nil || errors.Is(err, errParse) ||
+			(isStatusErr && !isRetryable(statusErr.StatusCode)) {
+			return nil, err
+		}
+
+		var retryAfter time.Duration
+		if throttled {
+			retryAfter = statusErr.RetryAfter
+		}
+
+		delay, ok := retryDelay(ctx, retryOptions, retry, retryAfter)
+		if !ok {
+			// the request can't be retried before the context expires
+			return nil, err
+		}
+		recordRetry(url.Host)
+
+		slog.Debug("retrying request", "url", url.String(), "retry", retry+1, "delay", delay.String(),
+			"error", err)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("retry", retry+1), attribute.String("error", err.Error())))
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// performAttempt performs and parses a single request, which must complete within the timeout.
+func performAttempt[T any](ctx context.Context, token string, url *url.URL, responseParser ResponseParser[T],
+	timeout time.Duration) (*T, error) {
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	response, err := performRequest(ctx, token, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to peform request: %v, %w", url, err)
@@ -53,7 +126,7 @@ func PerformRequest[T any](ctx context.Context, token string, url *url.URL,
 
 	parsed, err := responseParser(response)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, fmt.Errorf("%w: %w", errParse, err)
 	}
 
 	return parsed, nil
@@ -78,10 +151,12 @@ func performRequest(ctx context.Context, token string, url *url.URL) (*http.Resp
 		body, _ := io.ReadAll(res.Body)
 		defer closeBody(res)
 		slog.Debug("request failed", "status", res.StatusCode, "body", string(body))
+		retryAfter, _ := parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+		statusErr := &StatusError{StatusCode: res.StatusCode, RetryAfter: retryAfter}
 		if res.StatusCode == http.StatusUnauthorized {
-			return nil, fmt.Errorf("%w: request failed with status=%d", ErrAuthentication, res.StatusCode)
+			return nil, fmt.Errorf("%w: %w", ErrAuthentication, statusErr)
 		}
-		return nil, fmt.Errorf("request failed with status=%d", res.StatusCode)
+		return nil, statusErr
 	}
 	return res, nil
 }
@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryOptions configures how requests to the management endpoints are retried.
+type RetryOptions struct {
+	// MaxRetries is the number of times a failed request is retried.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry, which doubles with every further retry.
+	InitialBackoff time.Duration
+	// MaxBackoff limits the delay between two retries.
+	MaxBackoff time.Duration
+	// Timeout limits the duration of a single request.
+	Timeout time.Duration
+}
+
+// RequestStats counts the retried requests and throttled responses of an endpoint since the application started.
+// Throttled responses are counted even if the request isn't retried.
+type RequestStats struct {
+	Retries   int64
+	Throttles int64
+}
+
+var (
+	optionsMu sync.RWMutex
+	options   = RetryOptions{
+		MaxRetries:     3,                      //nolint:mnd // just a default
+		InitialBackoff: 500 * time.Millisecond, //nolint:mnd // just a default
+		MaxBackoff:     30 * time.Second,       //nolint:mnd // just a default
+		Timeout:        30 * time.Second,       //nolint:mnd // just a default
+	}
+
+	statsMu sync.Mutex
+	stats   = make(map[string]*RequestStats)
+)
+
+// Configure sets the retry options of all following requests.
+func Configure(retryOptions RetryOptions) {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	options = retryOptions
+}
+
+func getOptions() RetryOptions {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+	return options
+}
+
+// Stats returns the request statistics per endpoint.
+func Stats() map[string]RequestStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	result := make(map[string]RequestStats, len(stats))
+	for endpoint, endpointStats := range stats {
+		result[endpoint] = *endpointStats
+	}
+	return result
+}
+
+func recordRetry(endpoint string) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	getStats(endpoint).Retries++
+}
+
+func recordThrottle(endpoint string) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	getStats(endpoint).Throttles++
+}
+
+// getStats returns the statistics of the endpoint, which requires statsMu to be locked.
+func getStats(endpoint string) *RequestStats {
+	endpointStats, ok := stats[endpoint]
+	if !ok {
+		endpointStats = &RequestStats{}
+		stats[endpoint] = endpointStats
+	}
+	return endpointStats
+}
+
+// isRetryable reports whether a request which failed with the given status code may succeed when retried.
+func isRetryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func isThrottled(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// backoff returns the jittered delay before the given retry, starting with 0.
+func backoff(retryOptions RetryOptions, retry int) time.Duration {
+	delay := retryOptions.InitialBackoff
+	for range retry {
+		delay *= 2
+		if delay >= retryOptions.MaxBackoff {
+			delay = retryOptions.MaxBackoff
+			break
+		}
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	// equal jitter: wait at least half of the delay
+	return delay/2 + rand.N(delay/2+1) //nolint:gosec // jitter doesn't need a secure random number
+}
+
+// retryDelay returns the delay before the given retry. The Retry-After delay requested by a throttling server is
+// limited to MaxBackoff. It returns false if the delay ends after the deadline of the context.
+func retryDelay(ctx context.Context, retryOptions RetryOptions, retry int, retryAfter time.Duration) (
+	time.Duration, bool) {
+
+	delay := backoff(retryOptions, retry)
+	if retryAfter > 0 {
+		delay = retryAfter
+		if retryOptions.MaxBackoff > 0 {
+			delay = min(delay, retryOptions.MaxBackoff)
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return delay, false
+	}
+	return delay, true
+}
+
+// parseRetryAfter reads the delay the server asks for, given in seconds or as http date.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func parseStatus(response *http.Response) (*int, error) {
+	return &response.StatusCode, nil
+}
+
+func TestPerformRequestRetriesThrottledRequests(t *testing.T) {
+	Configure(RetryOptions{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond,
+		Timeout: time.Second})
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	status, err := PerformRequest(context.Background(), "token", serverURL, parseStatus)
+	if err != nil {
+		t.Fatalf("failed to perform request: %v", err)
+	}
+
+	if *status != http.StatusOK || requests.Load() != 2 {
+		t.Fatalf("expected a successful retry, got status %d after %d requests", *status, requests.Load())
+	}
+
+	if stats := Stats()[serverURL.Host]; stats.Retries != 1 || stats.Throttles != 1 {
+		t.Fatalf("expected 1 retry and 1 throttle, got %+v", stats)
+	}
+}
+
+func TestPerformRequestDoesNotRetryClientErrors(t *testing.T) {
+	Configure(RetryOptions{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond,
+		Timeout: time.Second})
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	_, err := PerformRequest(context.Background(), "token", serverURL, parseStatus)
+	if !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("expected %v, got %v", ErrAuthentication, err)
+	}
+
+	if requests.Load() != 1 {
+		t.Fatalf("expected 1 request, got %d", requests.Load())
+	}
+}
+
+func TestPerformRequestCountsThrottlesWithoutRetries(t *testing.T) {
+	Configure(RetryOptions{MaxRetries: 0, Timeout: time.Second})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	if _, err := PerformRequest(context.Background(), "token", serverURL, parseStatus); err == nil {
+		t.Fatal("expected the throttled request to fail")
+	}
+
+	if stats := Stats()[serverURL.Host]; stats.Retries != 0 || stats.Throttles != 1 {
+		t.Fatalf("expected no retry and 1 throttle, got %+v", stats)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	retryOptions := RetryOptions{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	if delay, ok := retryDelay(context.Background(), retryOptions, 0, time.Hour); !ok || delay != time.Second {
+		t.Errorf("expected Retry-After to be limited to %v, got %v (%t)", time.Second, delay, ok)
+	}
+
+	if delay, ok := retryDelay(context.Background(), retryOptions, 0, 500*time.Millisecond); !ok ||
+		delay != 500*time.Millisecond {
+		t.Errorf("expected Retry-After of %v, got %v (%t)", 500*time.Millisecond, delay, ok)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, ok := retryDelay(ctx, retryOptions, 0, time.Hour); ok {
+		t.Error("expected no retry after the deadline of the context")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	retryOptions := RetryOptions{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		retry    int
+		expected time.Duration
+	}{
+		{retry: 0, expected: 100 * time.Millisecond},
+		{retry: 2, expected: 400 * time.Millisecond},
+		{retry: 10, expected: time.Second},
+	}
+
+	for _, test := range tests {
+		delay := backoff(retryOptions, test.retry)
+		if delay < test.expected/2 || delay > test.expected {
+			t.Errorf("expected delay of retry %d between %v and %v, got %v", test.retry, test.expected/2,
+				test.expected, delay)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		header   string
+		expected time.Duration
+		ok       bool
+	}{
+		{header: "", ok: false},
+		{header: "5", expected: 5 * time.Second, ok: true},
+		{header: "Mon, 01 Jan 2024 12:00:10 GMT", expected: 10 * time.Second, ok: true},
+		{header: "Mon, 01 Jan 2024 11:00:00 GMT", expected: 0, ok: true},
+		{header: "soon", ok: false},
+	}
+
+	for _, test := range tests {
+		delay, ok := parseRetryAfter(test.header, now)
+		if delay != test.expected || ok != test.ok {
+			t.Errorf("expected %v/%v for %q, got %v/%v", test.expected, test.ok, test.header, delay, ok)
+		}
+	}
+}
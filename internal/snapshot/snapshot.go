@@ -0,0 +1,354 @@
+package snapshot
+
+import (
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/deviceinsight/eventhub-metrics/internal/metrics"
+)
+
+// Snapshot is the state of all namespaces as of the last completed collection cycle.
+type Snapshot struct {
+	CompletedAt time.Time `json:"completedAt"`
+	// Partial is true if the cycle exceeded its timeout or targets failed.
+	Partial    bool        `json:"partial"`
+	Namespaces []Namespace `json:"namespaces"`
+}
+
+type Namespace struct {
+	Name      string     `json:"name"`
+	Endpoint  string     `json:"endpoint"`
+	EventHubs []EventHub `json:"eventHubs"`
+}
+
+type EventHub struct {
+	Name            string          `json:"name"`
+	PartitionCount  int             `json:"partitionCount"`
+	RetentionInDays int             `json:"retentionInDays"`
+	Partitions      []Partition     `json:"partitions"`
+	ConsumerGroups  []ConsumerGroup `json:"consumerGroups"`
+}
+
+type Partition struct {
+	ID          string `json:"id"`
+	SequenceMin int64  `json:"sequenceMin"`
+	SequenceMax int64  `json:"sequenceMax"`
+}
+
+type ConsumerGroup struct {
+	Name       string                   `json:"name"`
+	Protocol   string                   `json:"protocol"`
+	State      string                   `json:"state,omitempty"`
+	Owners     int                      `json:"owners"`
+	Lag        int64                    `json:"lag"`
+	LagSeconds *float64                 `json:"lagSeconds,omitempty"`
+	Partitions []ConsumerGroupPartition `json:"partitions"`
+}
+
+type ConsumerGroupPartition struct {
+	ID           string   `json:"id"`
+	Lag          int64    `json:"lag"`
+	LagSeconds   *float64 `json:"lagSeconds,omitempty"`
+	Owner        string   `json:"owner,omitempty"`
+	OwnerExpired bool     `json:"ownerExpired,omitempty"`
+}
+
+// Service records the snapshot of a collection cycle alongside the metrics, which it passes on to the wrapped
+// service. The snapshot is published once the metrics of the cycle were pushed.
+type Service struct {
+	metrics.Service
+
+	mu        sync.Mutex
+	building  *builder
+	published *Snapshot
+}
+
+var _ metrics.Service = (*Service)(nil)
+
+func NewService(delegate metrics.Service) *Service {
+	return &Service{Service: delegate, building: newBuilder()}
+}
+
+// Get returns the snapshot of the last completed collection cycle and false if no cycle has been completed yet.
+func (s *Service) Get() (*Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.published, s.published != nil
+}
+
+// ServeHTTP returns the snapshot of the last completed collection cycle as JSON.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot, ok := s.Get()
+	if !ok {
+		http.Error(w, "no collection cycle completed yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		slog.Warn("failed to write snapshot", "error", err)
+	}
+}
+
+func (s *Service) StartCollectionCycle() {
+	s.mu.Lock()
+	s.building = newBuilder()
+	s.mu.Unlock()
+
+	s.Service.StartCollectionCycle()
+}
+
+// PushMetrics publishes the snapshot, even if pushing the metrics to an exporter failed.
+func (s *Service) PushMetrics() error {
+	err := s.Service.PushMetrics()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.published = s.building.build(time.Now())
+	return err
+}
+
+func (s *Service) RecordCollectionCycle(duration time.Duration, partial bool) {
+	s.update(func(b *builder) { b.partial = partial })
+	s.Service.RecordCollectionCycle(duration, partial)
+}
+
+func (s *Service) RecordNamespaceInfo(namespace, endpoint string) {
+	s.update(func(b *builder) { b.namespace(namespace).Endpoint = endpoint })
+	s.Service.RecordNamespaceInfo(namespace, endpoint)
+}
+
+func (s *Service) RecordEventhubInfo(namespace, eventhub string, partitionCount, messageRetentionInDays int) {
+	s.update(func(b *builder) {
+		hub := b.eventHub(namespace, eventhub)
+		hub.PartitionCount = partitionCount
+		hub.RetentionInDays = messageRetentionInDays
+	})
+	s.Service.RecordEventhubInfo(namespace, eventhub, partitionCount, messageRetentionInDays)
+}
+
+func (s *Service) RecordEventhubPartitionSequenceNumber(namespace, eventhub, partitionID string, seqMin,
+	seqMax int64) {
+
+	s.update(func(b *builder) {
+		b.eventHubBuilder(namespace, eventhub).partitions[partitionID] = &Partition{ID: partitionID,
+			SequenceMin: seqMin, SequenceMax: seqMax}
+	})
+	s.Service.RecordEventhubPartitionSequenceNumber(namespace, eventhub, partitionID, seqMin, seqMax)
+}
+
+func (s *Service) RecordConsumerGroupInfo(namespace, eventhub, consumerGroup, protocol, state string) {
+	s.update(func(b *builder) { b.consumerGroup(namespace, eventhub, consumerGroup, protocol).State = state })
+	s.Service.RecordConsumerGroupInfo(namespace, eventhub, consumerGroup, protocol, state)
+}
+
+func (s *Service) RecordConsumerGroupOwners(namespace, eventhub, consumerGroup string, ownerCount int) {
+	s.update(func(b *builder) {
+		b.consumerGroup(namespace, eventhub, consumerGroup, metrics.ProtocolEventHubs).Owners = ownerCount
+	})
+	s.Service.RecordConsumerGroupOwners(namespace, eventhub, consumerGroup, ownerCount)
+}
+
+func (s *Service) RecordConsumerGroupPartitionOwner(namespace, eventhub, consumerGroup, partitionID, owner string,
+	expired bool) {
+
+	s.update(func(b *builder) {
+		partition := b.consumerGroupPartition(namespace, eventhub, consumerGroup, metrics.ProtocolEventHubs,
+			partitionID)
+		partition.Owner = owner
+		partition.OwnerExpired = expired
+	})
+	s.Service.RecordConsumerGroupPartitionOwner(namespace, eventhub, consumerGroup, partitionID, owner, expired)
+}
+
+func (s *Service) RecordConsumerGroupPartitionLag(namespace, eventhub, consumerGroup, partitionID, protocol string,
+	lag int64) {
+
+	s.update(func(b *builder) {
+		b.consumerGroupPartition(namespace, eventhub, consumerGroup, protocol, partitionID).Lag = lag
+	})
+	s.Service.RecordConsumerGroupPartitionLag(namespace, eventhub, consumerGroup, partitionID, protocol, lag)
+}
+
+func (s *Service) RecordConsumerGroupLag(namespace, eventhub, consumerGroup, protocol string, lag int64) {
+	s.update(func(b *builder) { b.consumerGroup(namespace, eventhub, consumerGroup, protocol).Lag = lag })
+	s.Service.RecordConsumerGroupLag(namespace, eventhub, consumerGroup, protocol, lag)
+}
+
+func (s *Service) RecordConsumerGroupPartitionLagSeconds(namespace, eventhub, consumerGroup, partitionID string,
+	lagSeconds float64) {
+
+	s.update(func(b *builder) {
+		b.consumerGroupPartition(namespace, eventhub, consumerGroup, metrics.ProtocolEventHubs,
+			partitionID).LagSeconds = &lagSeconds
+	})
+	s.Service.RecordConsumerGroupPartitionLagSeconds(namespace, eventhub, consumerGroup, partitionID, lagSeconds)
+}
+
+func (s *Service) RecordConsumerGroupLagSeconds(namespace, eventhub, consumerGroup string, lagSeconds float64) {
+	s.update(func(b *builder) {
+		b.consumerGroup(namespace, eventhub, consumerGroup, metrics.ProtocolEventHubs).LagSeconds = &lagSeconds
+	})
+	s.Service.RecordConsumerGroupLagSeconds(namespace, eventhub, consumerGroup, lagSeconds)
+}
+
+func (s *Service) update(apply func(b *builder)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	apply(s.building)
+}
+
+// builder collects the snapshot of the running cycle, keyed by name since the metrics arrive in any order.
+type builder struct {
+	partial    bool
+	namespaces map[string]*namespaceBuilder
+}
+
+type namespaceBuilder struct {
+	Namespace
+	eventHubs map[string]*eventHubBuilder
+}
+
+type eventHubBuilder struct {
+	EventHub
+	partitions     map[string]*Partition
+	consumerGroups map[consumerGroupKey]*consumerGroupBuilder
+}
+
+type consumerGroupKey struct {
+	name     string
+	protocol string
+}
+
+type consumerGroupBuilder struct {
+	ConsumerGroup
+	partitions map[string]*ConsumerGroupPartition
+}
+
+func newBuilder() *builder {
+	return &builder{namespaces: make(map[string]*namespaceBuilder)}
+}
+
+func (b *builder) namespace(namespace string) *namespaceBuilder {
+	ns, ok := b.namespaces[namespace]
+	if !ok {
+		ns = &namespaceBuilder{Namespace: Namespace{Name: namespace}, eventHubs: make(map[string]*eventHubBuilder)}
+		b.namespaces[namespace] = ns
+	}
+	return ns
+}
+
+func (b *builder) eventHub(namespace, eventHub string) *EventHub {
+	return &b.eventHubBuilder(namespace, eventHub).EventHub
+}
+
+func (b *builder) eventHubBuilder(namespace, eventHub string) *eventHubBuilder {
+	ns := b.namespace(namespace)
+	hub, ok := ns.eventHubs[eventHub]
+	if !ok {
+		hub = &eventHubBuilder{
+			EventHub:       EventHub{Name: eventHub},
+			partitions:     make(map[string]*Partition),
+			consumerGroups: make(map[consumerGroupKey]*consumerGroupBuilder),
+		}
+		ns.eventHubs[eventHub] = hub
+	}
+	return hub
+}
+
+func (b *builder) consumerGroup(namespace, eventHub, consumerGroup, protocol string) *ConsumerGroup {
+	return &b.consumerGroupBuilder(namespace, eventHub, consumerGroup, protocol).ConsumerGroup
+}
+
+func (b *builder) consumerGroupBuilder(namespace, eventHub, consumerGroup, protocol string) *consumerGroupBuilder {
+	hub := b.eventHubBuilder(namespace, eventHub)
+	key := consumerGroupKey{name: consumerGroup, protocol: protocol}
+	group, ok := hub.consumerGroups[key]
+	if !ok {
+		group = &consumerGroupBuilder{
+			ConsumerGroup: ConsumerGroup{Name: consumerGroup, Protocol: protocol},
+			partitions:    make(map[string]*ConsumerGroupPartition),
+		}
+		hub.consumerGroups[key] = group
+	}
+	return group
+}
+
+func (b *builder) consumerGroupPartition(namespace, eventHub, consumerGroup, protocol,
+	partitionID string) *ConsumerGroupPartition {
+
+	group := b.consumerGroupBuilder(namespace, eventHub, consumerGroup, protocol)
+	partition, ok := group.partitions[partitionID]
+	if !ok {
+		partition = &ConsumerGroupPartition{ID: partitionID}
+		group.partitions[partitionID] = partition
+	}
+	return partition
+}
+
+// build returns the snapshot with all entries sorted by name.
+func (b *builder) build(completedAt time.Time) *Snapshot {
+	snapshot := &Snapshot{CompletedAt: completedAt, Partial: b.partial, Namespaces: make([]Namespace, 0)}
+
+	for _, nsName := range slices.Sorted(maps.Keys(b.namespaces)) {
+		ns := b.namespaces[nsName]
+		namespace := ns.Namespace
+		namespace.EventHubs = make([]EventHub, 0, len(ns.eventHubs))
+
+		for _, hubName := range slices.Sorted(maps.Keys(ns.eventHubs)) {
+			namespace.EventHubs = append(namespace.EventHubs, ns.eventHubs[hubName].build())
+		}
+		snapshot.Namespaces = append(snapshot.Namespaces, namespace)
+	}
+
+	return snapshot
+}
+
+func (b *eventHubBuilder) build() EventHub {
+	hub := b.EventHub
+	hub.Partitions = sortedValues(b.partitions, func(p *Partition) string { return p.ID })
+	hub.ConsumerGroups = make([]ConsumerGroup, 0, len(b.consumerGroups))
+
+	for _, group := range b.consumerGroups {
+		consumerGroup := group.ConsumerGroup
+		consumerGroup.Partitions = sortedValues(group.partitions,
+			func(p *ConsumerGroupPartition) string { return p.ID })
+		hub.ConsumerGroups = append(hub.ConsumerGroups, consumerGroup)
+	}
+	slices.SortFunc(hub.ConsumerGroups, func(a, b ConsumerGroup) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Protocol, b.Protocol))
+	})
+
+	return hub
+}
+
+// sortedValues copies the values of a map, sorted by the given key. Partition ids are sorted numerically where
+// possible.
+func sortedValues[T any](values map[string]*T, key func(*T) string) []T {
+	result := make([]T, 0, len(values))
+	for _, value := range values {
+		result = append(result, *value)
+	}
+	slices.SortFunc(result, func(a, b T) int {
+		return comparePartitionIDs(key(&a), key(&b))
+	})
+	return result
+}
+
+func comparePartitionIDs(a, b string) int {
+	return cmp.Or(cmp.Compare(len(a), len(b)), cmp.Compare(a, b))
+}
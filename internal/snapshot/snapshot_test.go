@@ -0,0 +1,106 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deviceinsight/eventhub-metrics/internal/metrics"
+)
+
+func TestServicePublishesCompletedCycle(t *testing.T) {
+	service := NewService(metrics.NewDelegateService())
+
+	if rec := get(service); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected no snapshot before the first cycle, got %d", rec.Code)
+	}
+
+	service.StartCollectionCycle()
+	service.RecordNamespaceInfo("ns", "ns.servicebus.windows.net")
+	service.RecordEventhubInfo("ns", "hub", 2, 7)
+	service.RecordEventhubPartitionSequenceNumber("ns", "hub", "10", 5, 50)
+	service.RecordEventhubPartitionSequenceNumber("ns", "hub", "2", 0, 20)
+	service.RecordConsumerGroupInfo("ns", "hub", "cg", metrics.ProtocolEventHubs, "active")
+	service.RecordConsumerGroupOwners("ns", "hub", "cg", 1)
+	service.RecordConsumerGroupPartitionOwner("ns", "hub", "cg", "2", "owner-1", false)
+	service.RecordConsumerGroupPartitionLag("ns", "hub", "cg", "2", metrics.ProtocolEventHubs, 3)
+	service.RecordConsumerGroupLag("ns", "hub", "cg", metrics.ProtocolEventHubs, 3)
+	service.RecordConsumerGroupLag("ns", "hub", "cg", metrics.ProtocolKafka, 8)
+	service.RecordCollectionCycle(time.Second, true)
+
+	if rec := get(service); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the running cycle not to be published, got %d", rec.Code)
+	}
+
+	if err := service.PushMetrics(); err != nil {
+		t.Fatalf("failed to push metrics: %v", err)
+	}
+
+	// the next cycle must not affect the published snapshot until it is completed
+	service.StartCollectionCycle()
+	service.RecordNamespaceInfo("other", "other.servicebus.windows.net")
+
+	rec := get(service)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the snapshot to be published, got %d", rec.Code)
+	}
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(rec.Body).Decode(&snapshot); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+
+	if !snapshot.Partial || len(snapshot.Namespaces) != 1 || len(snapshot.Namespaces[0].EventHubs) != 1 {
+		t.Fatalf("unexpected snapshot: %+v", snapshot)
+	}
+
+	hub := snapshot.Namespaces[0].EventHubs[0]
+	if hub.PartitionCount != 2 || len(hub.Partitions) != 2 || hub.Partitions[0].ID != "2" ||
+		hub.Partitions[1].SequenceMax != 50 {
+		t.Fatalf("unexpected partitions: %+v", hub)
+	}
+
+	if len(hub.ConsumerGroups) != 2 {
+		t.Fatalf("expected a consumer group per protocol, got %+v", hub.ConsumerGroups)
+	}
+	group := hub.ConsumerGroups[0]
+	if group.Protocol != metrics.ProtocolEventHubs || group.State != "active" || group.Lag != 3 ||
+		len(group.Partitions) != 1 || group.Partitions[0].Owner != "owner-1" {
+		t.Fatalf("unexpected consumer group: %+v", group)
+	}
+	if hub.ConsumerGroups[1].Protocol != metrics.ProtocolKafka || hub.ConsumerGroups[1].Lag != 8 {
+		t.Fatalf("unexpected kafka consumer group: %+v", hub.ConsumerGroups[1])
+	}
+}
+
+func TestServicePublishesSnapshotDespiteFailedPush(t *testing.T) {
+	service := NewService(failingService{metrics.NewDelegateService()})
+
+	service.StartCollectionCycle()
+	service.RecordNamespaceInfo("ns", "ns.servicebus.windows.net")
+	if err := service.PushMetrics(); err == nil {
+		t.Fatal("expected the failed push to be returned")
+	}
+
+	if rec := get(service); rec.Code != http.StatusOK {
+		t.Fatalf("expected the snapshot to be published, got %d", rec.Code)
+	}
+}
+
+// failingService fails to push the metrics.
+type failingService struct {
+	metrics.Service
+}
+
+func (failingService) PushMetrics() error {
+	return errors.New("unavailable")
+}
+
+func get(handler http.Handler) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/consumergroups", nil))
+	return rec
+}
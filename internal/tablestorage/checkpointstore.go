@@ -0,0 +1,221 @@
+package tablestorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/v2"
+	"github.com/deviceinsight/eventhub-metrics/internal/blobstorage"
+	"github.com/deviceinsight/eventhub-metrics/internal/eventhub"
+	"github.com/deviceinsight/eventhub-metrics/internal/tracing"
+)
+
+const (
+	checkpointRowType = "Checkpoint"
+	ownershipRowType  = "Ownership"
+)
+
+// CheckpointStore reads the checkpoints and ownerships an Event Hubs processor keeps in a storage table.
+// It implements eventhub.CheckpointStore.
+//
+// Rows use the schema of the table checkpoint store libraries: the PartitionKey consists of
+// "<fully-qualified-namespace> <event-hub-name> <consumer-group> <Checkpoint|Ownership>" and the RowKey is the
+// partition id.
+type CheckpointStore struct {
+	client *aztables.Client
+	table  string
+}
+
+func newCheckpointStore(client *aztables.Client, table string) *CheckpointStore {
+	return &CheckpointStore{client: client, table: table}
+}
+
+func (s *CheckpointStore) ListCheckpoints(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]eventhub.Checkpoint, error) {
+
+	ctx, span := tracing.Start(ctx, "tablestorage.ListCheckpoints",
+		tracing.AttributeTable.String(s.table), tracing.AttributeConsumerGroup.String(consumerGroup))
+	checkpoints, err := s.listCheckpoints(ctx, namespace, eventHub, consumerGroup)
+	tracing.End(span, err)
+	return checkpoints, err
+}
+
+func (s *CheckpointStore) listCheckpoints(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]eventhub.Checkpoint, error) {
+
+	entities, err := s.listEntities(ctx, namespace, eventHub, consumerGroup, checkpointRowType)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpointList := make([]eventhub.Checkpoint, 0, len(entities))
+
+	for _, e := range entities {
+		if e.SequenceNumber == nil {
+			return nil, fmt.Errorf("invalid checkpoint row %s: SequenceNumber is missing", e.RowKey)
+		}
+
+		sequenceNumber, err := strconv.ParseInt(string(*e.SequenceNumber), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checkpoint row %s: SequenceNumber could not be parsed as an int64: %w",
+				e.RowKey, err)
+		}
+
+		checkpoint := eventhub.Checkpoint{
+			Checkpoint: azeventhubs.Checkpoint{
+				FullyQualifiedNamespace: namespace,
+				EventHubName:            eventHub,
+				ConsumerGroup:           consumerGroup,
+				PartitionID:             e.RowKey,
+				SequenceNumber:          &sequenceNumber,
+			},
+		}
+
+		if e.Offset != nil {
+			offset := string(*e.Offset)
+			checkpoint.Offset = &offset
+		}
+
+		if !e.Timestamp.IsZero() {
+			checkpoint.LastModified = &e.Timestamp
+		}
+
+		checkpointList = append(checkpointList, checkpoint)
+	}
+
+	return checkpointList, nil
+}
+
+func (s *CheckpointStore) ListOwnership(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]azeventhubs.Ownership, error) {
+
+	ctx, span := tracing.Start(ctx, "tablestorage.ListOwnership",
+		tracing.AttributeTable.String(s.table), tracing.AttributeConsumerGroup.String(consumerGroup))
+	ownerships, err := s.listOwnership(ctx, namespace, eventHub, consumerGroup)
+	tracing.End(span, err)
+	return ownerships, err
+}
+
+func (s *CheckpointStore) listOwnership(ctx context.Context, namespace, eventHub,
+	consumerGroup string) ([]azeventhubs.Ownership, error) {
+
+	entities, err := s.listEntities(ctx, namespace, eventHub, consumerGroup, ownershipRowType)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerships := make([]azeventhubs.Ownership, 0, len(entities))
+
+	for _, e := range entities {
+		ownership := azeventhubs.Ownership{
+			FullyQualifiedNamespace: namespace,
+			EventHubName:            eventHub,
+			ConsumerGroup:           consumerGroup,
+			PartitionID:             e.RowKey,
+			OwnerID:                 e.OwnerID,
+			LastModifiedTime:        e.Timestamp,
+		}
+
+		if e.ETag != "" {
+			etag := azcore.ETag(e.ETag)
+			ownership.ETag = &etag
+		}
+
+		ownerships = append(ownerships, ownership)
+	}
+
+	return ownerships, nil
+}
+
+func (s *CheckpointStore) listEntities(ctx context.Context, namespace, eventHub, consumerGroup,
+	rowType string) ([]entity, error) {
+
+	partitionKey := strings.Join([]string{namespace, eventHub, consumerGroup, rowType}, " ")
+	filter := fmt.Sprintf("PartitionKey eq '%s'", strings.ReplaceAll(partitionKey, "'", "''"))
+
+	pager := s.client.NewListEntitiesPager(&aztables.ListEntitiesOptions{
+		Filter: &filter,
+	})
+
+	var entities []entity
+
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, data := range resp.Entities {
+			e, err := parseEntity(data)
+			if err != nil {
+				return nil, err
+			}
+			entities = append(entities, e)
+		}
+	}
+
+	return entities, nil
+}
+
+// entity contains the properties of checkpoint and ownership rows.
+type entity struct {
+	PartitionKey   string
+	RowKey         string
+	Timestamp      time.Time
+	ETag           string `json:"odata.etag"`
+	Offset         *entityValue
+	SequenceNumber *entityValue
+	OwnerID        string `json:"OwnerId"`
+}
+
+func parseEntity(data []byte) (entity, error) {
+	var e entity
+	if err := json.Unmarshal(data, &e); err != nil {
+		return entity{}, fmt.Errorf("failed to parse table entity: %w", err)
+	}
+	return e, nil
+}
+
+// entityValue is a property which is either stored as a number or as a string. Edm.Int64 values are always
+// serialized as strings, while libraries write small sequence numbers as Edm.Int32 numbers.
+type entityValue string
+
+func (v *entityValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = entityValue(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return errors.New("value is neither a string nor a number")
+	}
+	*v = entityValue(n)
+	return nil
+}
+
+// parsePartitionKey splits the PartitionKey of a checkpoint or ownership row.
+func parsePartitionKey(partitionKey string) (blobstorage.StoredConsumerGroup, bool) {
+	parts := strings.Split(partitionKey, " ")
+	if len(parts) != 4 { //nolint:mnd // namespace, eventhub, consumer group and row type
+		return blobstorage.StoredConsumerGroup{}, false
+	}
+
+	if rowType := parts[3]; rowType != checkpointRowType && rowType != ownershipRowType {
+		return blobstorage.StoredConsumerGroup{}, false
+	}
+
+	return blobstorage.StoredConsumerGroup{
+		Namespace:     parts[0],
+		Eventhub:      parts[1],
+		ConsumerGroup: parts[2],
+	}, true
+}
@@ -0,0 +1,188 @@
+package tablestorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/deviceinsight/eventhub-metrics/internal/blobstorage"
+	"github.com/deviceinsight/eventhub-metrics/internal/eventhub"
+	"github.com/deviceinsight/eventhub-metrics/internal/rest"
+)
+
+type StorageTable struct {
+	Endpoint string
+	Table    string
+}
+
+type StoredGroupsMap = map[StorageTable][]blobstorage.StoredConsumerGroup
+
+// GetTableInfos discovers the consumer groups whose checkpoints or ownerships are stored in the tables of a storage
+// account.
+func GetTableInfos(ctx context.Context, credential *azidentity.DefaultAzureCredential, endpoint string,
+	includedTablesRegex, excludedTablesRegex *regexp.Regexp) (StoredGroupsMap, error) {
+
+	serviceClient, err := getServiceClient(credential, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return getTableInfos(ctx, serviceClient, endpoint, includedTablesRegex, excludedTablesRegex)
+}
+
+func getTableInfos(ctx context.Context, serviceClient *aztables.ServiceClient, endpoint string,
+	includedTablesRegex, excludedTablesRegex *regexp.Regexp) (StoredGroupsMap, error) {
+
+	tables, err := getTables(ctx, serviceClient, includedTablesRegex, excludedTablesRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make(StoredGroupsMap)
+
+	for _, tableName := range tables {
+		storedConsumerGroups, err := listConsumerGroups(ctx, serviceClient.NewClient(tableName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list consumer groups of table %s: %w", tableName, err)
+		}
+
+		if len(storedConsumerGroups) == 0 {
+			slog.Debug("ignoring table without checkpoints", "endpoint", endpoint, "table", tableName)
+			continue
+		}
+
+		infos[StorageTable{
+			Endpoint: endpoint,
+			Table:    tableName,
+		}] = storedConsumerGroups
+	}
+
+	return infos, nil
+}
+
+// listConsumerGroups reads the partition keys of all rows and returns the distinct consumer groups they belong to.
+func listConsumerGroups(ctx context.Context, client *aztables.Client) ([]blobstorage.StoredConsumerGroup, error) {
+	pager := client.NewListEntitiesPager(&aztables.ListEntitiesOptions{
+		Select: to.Ptr("PartitionKey"),
+	})
+
+	storedConsumerGroups := make([]blobstorage.StoredConsumerGroup, 0)
+	seen := make(map[blobstorage.StoredConsumerGroup]bool)
+
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, data := range resp.Entities {
+			e, err := parseEntity(data)
+			if err != nil {
+				return nil, err
+			}
+
+			storedConsumerGroup, ok := parsePartitionKey(e.PartitionKey)
+			if !ok {
+				slog.Debug("ignoring invalid table row", "partitionKey", e.PartitionKey)
+				continue
+			}
+
+			if !seen[storedConsumerGroup] {
+				seen[storedConsumerGroup] = true
+				storedConsumerGroups = append(storedConsumerGroups, storedConsumerGroup)
+			}
+		}
+	}
+
+	return storedConsumerGroups, nil
+}
+
+func getTables(ctx context.Context, client *aztables.ServiceClient,
+	includedTablesRegex, excludedTablesRegex *regexp.Regexp) ([]string, error) {
+
+	pager := client.NewListTablesPager(nil)
+	tables := make([]string, 0)
+
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			var respErr *azcore.ResponseError
+			if errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden {
+				return nil, fmt.Errorf("%w: %w", rest.ErrAuthentication, err)
+			}
+			return nil, err
+		}
+
+		for _, table := range resp.Tables {
+
+			tableName := *table.Name
+
+			if includedTablesRegex != nil && !includedTablesRegex.MatchString(tableName) {
+				slog.Debug("skipping non-included table", "table", tableName,
+					"regex", includedTablesRegex.String())
+				continue
+			}
+
+			if excludedTablesRegex != nil && excludedTablesRegex.MatchString(tableName) {
+				slog.Debug("skipping excluded table", "table", tableName,
+					"regex", excludedTablesRegex.String())
+				continue
+			}
+
+			tables = append(tables, tableName)
+		}
+	}
+
+	return tables, nil
+}
+
+// GetCheckpointStores returns the checkpoint stores of all consumer groups of an eventhub which are stored in tables.
+func GetCheckpointStores(credential *azidentity.DefaultAzureCredential, storedGroupsMap StoredGroupsMap,
+	namespace, eventHub string) (map[string]eventhub.CheckpointStore, error) {
+
+	consumerGroupStores := make(map[string]eventhub.CheckpointStore)
+
+	for storageTable, storedConsumerGroups := range storedGroupsMap {
+
+		consumerGroups := make([]string, 0)
+
+		for _, storedConsumerGroup := range storedConsumerGroups {
+			if storedConsumerGroup.Namespace == namespace && storedConsumerGroup.Eventhub == eventHub {
+				consumerGroups = append(consumerGroups, storedConsumerGroup.ConsumerGroup)
+			}
+		}
+
+		if len(consumerGroups) > 0 {
+			serviceClient, err := getServiceClient(credential, storageTable.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("unable to create table store for endpoint=%s, error=%w",
+					storageTable.Endpoint, err)
+			}
+
+			store := newCheckpointStore(serviceClient.NewClient(storageTable.Table), storageTable.Table)
+
+			for _, consumerGroup := range consumerGroups {
+				consumerGroupStores[consumerGroup] = store
+			}
+		}
+	}
+
+	return consumerGroupStores, nil
+}
+
+func getServiceClient(credential *azidentity.DefaultAzureCredential,
+	endpoint string) (*aztables.ServiceClient, error) {
+
+	serviceClient, err := aztables.NewServiceClient(fmt.Sprintf("https://%s", endpoint), credential, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating table service client: %w", err)
+	}
+	return serviceClient, nil
+}
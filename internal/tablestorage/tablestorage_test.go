@@ -0,0 +1,122 @@
+package tablestorage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+)
+
+const testNamespace = "my-ns.servicebus.windows.net"
+
+func TestParseEntity(t *testing.T) {
+	e, err := parseEntity([]byte(`{"odata.etag":"W/\"1\"","PartitionKey":"my-ns.servicebus.windows.net eh cg Checkpoint",
+		"RowKey":"0","Timestamp":"2024-05-01T10:00:00.1234567Z","Offset":"4096",
+		"SequenceNumber@odata.type":"Edm.Int64","SequenceNumber":"9223372036854775807"}`))
+	if err != nil {
+		t.Fatalf("failed to parse entity: %v", err)
+	}
+
+	if e.SequenceNumber == nil || *e.SequenceNumber != "9223372036854775807" {
+		t.Errorf("unexpected sequence number %v", e.SequenceNumber)
+	}
+	if e.Offset == nil || *e.Offset != "4096" {
+		t.Errorf("unexpected offset %v", e.Offset)
+	}
+	if e.Timestamp.IsZero() {
+		t.Error("expected timestamp to be parsed")
+	}
+
+	e, err = parseEntity([]byte(`{"RowKey":"1","SequenceNumber":42}`))
+	if err != nil {
+		t.Fatalf("failed to parse entity: %v", err)
+	}
+	if e.SequenceNumber == nil || *e.SequenceNumber != "42" {
+		t.Errorf("unexpected sequence number %v", e.SequenceNumber)
+	}
+}
+
+func TestParsePartitionKey(t *testing.T) {
+	group, ok := parsePartitionKey("my-ns.servicebus.windows.net eh cg Ownership")
+	if !ok || group.Namespace != testNamespace || group.Eventhub != "eh" || group.ConsumerGroup != "cg" {
+		t.Errorf("unexpected consumer group %+v (ok: %t)", group, ok)
+	}
+
+	for _, partitionKey := range []string{"my-ns.servicebus.windows.net eh cg", "ns eh cg Lease"} {
+		if _, ok := parsePartitionKey(partitionKey); ok {
+			t.Errorf("expected %q to be invalid", partitionKey)
+		}
+	}
+}
+
+// TestCheckpointStoreWithAzurite requires a running Azurite, e.g.
+// AZURITE_CONNECTION_STRING="DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey=...;
+// TableEndpoint=http://127.0.0.1:10002/devstoreaccount1;".
+func TestCheckpointStoreWithAzurite(t *testing.T) {
+	connectionString := os.Getenv("AZURITE_CONNECTION_STRING")
+	if connectionString == "" {
+		t.Skip("AZURITE_CONNECTION_STRING is not set")
+	}
+
+	ctx := context.Background()
+
+	serviceClient, err := aztables.NewServiceClientFromConnectionString(connectionString, nil)
+	if err != nil {
+		t.Fatalf("failed to create service client: %v", err)
+	}
+
+	table := "checkpoints"
+	if _, err := serviceClient.CreateTable(ctx, table, nil); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	defer func() {
+		_, _ = serviceClient.DeleteTable(ctx, table, nil)
+	}()
+
+	client := serviceClient.NewClient(table)
+	for _, properties := range []map[string]any{
+		{"PartitionKey": testNamespace + " eh cg Checkpoint", "RowKey": "0", "Offset": "100", "SequenceNumber": 5},
+		{"PartitionKey": testNamespace + " eh cg Checkpoint", "RowKey": "1", "Offset": "200",
+			"SequenceNumber": "20", "SequenceNumber@odata.type": "Edm.Int64"},
+		{"PartitionKey": testNamespace + " eh cg Ownership", "RowKey": "0", "OwnerId": "owner-a"},
+		{"PartitionKey": "unrelated row", "RowKey": "0"},
+	} {
+		data, err := json.Marshal(properties)
+		if err != nil {
+			t.Fatalf("failed to marshal entity: %v", err)
+		}
+		if _, err := client.AddEntity(ctx, data, nil); err != nil {
+			t.Fatalf("failed to add entity: %v", err)
+		}
+	}
+
+	infos, err := getTableInfos(ctx, serviceClient, "azurite", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to get table infos: %v", err)
+	}
+	groups := infos[StorageTable{Endpoint: "azurite", Table: table}]
+	if len(groups) != 1 || groups[0].ConsumerGroup != "cg" {
+		t.Fatalf("expected consumer group cg to be discovered, got %+v", groups)
+	}
+
+	store := newCheckpointStore(client, table)
+
+	checkpoints, err := store.ListCheckpoints(ctx, testNamespace, "eh", "cg")
+	if err != nil {
+		t.Fatalf("failed to list checkpoints: %v", err)
+	}
+	if len(checkpoints) != 2 || *checkpoints[0].SequenceNumber != 5 || *checkpoints[1].SequenceNumber != 20 ||
+		checkpoints[0].LastModified == nil {
+		t.Errorf("unexpected checkpoints %+v", checkpoints)
+	}
+
+	ownerships, err := store.ListOwnership(ctx, testNamespace, "eh", "cg")
+	if err != nil {
+		t.Fatalf("failed to list ownerships: %v", err)
+	}
+	if len(ownerships) != 1 || ownerships[0].OwnerID != "owner-a" || ownerships[0].LastModifiedTime.IsZero() {
+		t.Errorf("unexpected ownerships %+v", ownerships)
+	}
+}
@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName  = "github.com/deviceinsight/eventhub-metrics"
+	serviceName = "eventhub-metrics"
+)
+
+// attribute keys of the spans. The namespace is its name, the endpoint its fully qualified domain name.
+const (
+	AttributeNamespace     = attribute.Key("eventhub.namespace")
+	AttributeEndpoint      = attribute.Key("eventhub.endpoint")
+	AttributeEventHub      = attribute.Key("eventhub.name")
+	AttributeConsumerGroup = attribute.Key("eventhub.consumer_group")
+	AttributePartitionID   = attribute.Key("eventhub.partition_id")
+	AttributeContainer     = attribute.Key("storage.container")
+	AttributeTable         = attribute.Key("storage.table")
+)
+
+// NewProvider registers a tracer provider which exports the spans to an OpenTelemetry collector. The returned
+// function flushes the pending spans and shuts the provider down.
+func NewProvider(baseURL, protocol, version string) (func(ctx context.Context) error, error) {
+	slog.Debug("using otlp tracing", "baseURL", baseURL, "protocol", protocol)
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch protocol {
+	case "grpc":
+		exporter, err = otlptracegrpc.New(context.Background(),
+			otlptracegrpc.WithEndpointURL(baseURL),
+		)
+	case "http":
+		var tracesURL string
+		tracesURL, err = getTracesURL(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		exporter, err = otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpointURL(tracesURL),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported otlp protocol: %q", protocol)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// getTracesURL returns the url the spans are sent to via http, since the base url may point to the metrics path.
+func getTracesURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse otlp url: %w", err)
+	}
+
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/v1/metrics") + "/v1/traces"
+	return u.String(), nil
+}
+
+// Start starts a span as child of the span contained in ctx. Without a registered tracer provider the span is a
+// no-op.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End records the error of the traced operation, if any, and ends the span.
+func End(span trace.Span, err error) {
+	SetError(span, err)
+	span.End()
+}
+
+// SetError marks a span as failed, if err isn't nil.
+func SetError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGetTracesURL(t *testing.T) {
+	tests := map[string]string{
+		"http://localhost:4318":             "http://localhost:4318/v1/traces",
+		"http://localhost:4318/":            "http://localhost:4318/v1/traces",
+		"http://localhost:4318/v1/metrics":  "http://localhost:4318/v1/traces",
+		"https://collector/otlp/v1/metrics": "https://collector/otlp/v1/traces",
+	}
+
+	for baseURL, expected := range tests {
+		tracesURL, err := getTracesURL(baseURL)
+		if err != nil {
+			t.Fatalf("failed to get traces url of %s: %v", baseURL, err)
+		}
+		if tracesURL != expected {
+			t.Errorf("expected %s for %s, got %s", expected, baseURL, tracesURL)
+		}
+	}
+}
+
+func TestEndRecordsError(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child", AttributeEventHub.String("eh"))
+	End(child, errors.New("failure"))
+	End(parent, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	if spans[0].Name != "child" || spans[0].Status.Code != codes.Error {
+		t.Errorf("expected the failed child span first, got %s with status %v", spans[0].Name, spans[0].Status)
+	}
+	if spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Error("expected the child span to be a child of the parent span")
+	}
+	if spans[1].Status.Code != codes.Unset {
+		t.Errorf("expected the parent span to succeed, got %v", spans[1].Status)
+	}
+}